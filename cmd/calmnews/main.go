@@ -2,13 +2,21 @@ package main
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"syscall"
 	"time"
+	// Embedded zone data, so ui.timezone works on systems without it installed
+	_ "time/tzdata"
+
+	"golang.org/x/crypto/bcrypt"
 
 	"calmnews/internal/config"
 	"calmnews/internal/feeds"
@@ -16,16 +24,26 @@ import (
 	"calmnews/internal/web"
 )
 
+// configReloadInterval is how often config.yaml is checked for changes
+const configReloadInterval = 5 * time.Second
+
+// logLevel is the minimum level logged. It follows CALMNEWS_LOG_LEVEL if set, otherwise
+// log_level in the config, and changes when the config is reloaded.
+var logLevel = new(slog.LevelVar)
+
 func main() {
+	// Structured key=value logs; the standard log package is routed here too
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+
 	// Get data directory
 	dataDir, err := config.DataDir()
 	if err != nil {
-		log.Fatalf("Failed to get data directory: %v", err)
+		fatal("Failed to get data directory", "err", err)
 	}
 
 	// Ensure data directory exists
 	if err := config.EnsureDataDir(); err != nil {
-		log.Fatalf("Failed to create data directory: %v", err)
+		fatal("Failed to create data directory", "err", err)
 	}
 
 	// Load or create config
@@ -33,83 +51,146 @@ func main() {
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			log.Printf("Config file not found, creating default config at %s", configPath)
+			slog.Info("Config file not found, creating default config", "path", configPath)
 			cfg = config.DefaultConfig()
 			if err := config.SaveConfig(configPath, cfg); err != nil {
-				log.Fatalf("Failed to save default config: %v", err)
+				fatal("Failed to save default config", "path", configPath, "err", err)
 			}
 		} else {
-			log.Fatalf("Failed to load config: %v", err)
+			fatal("Failed to load config", "path", configPath, "err", err)
 		}
 	}
 
+	if err := cfg.Validate(); err != nil {
+		fatal("Invalid config", "path", configPath, "err", err)
+	}
+	applyLogLevel(cfg)
+
 	// Initialize database
 	dbPath := filepath.Join(dataDir, "news.db")
 	db, err := storage.InitDB(dbPath)
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		fatal("Failed to initialize database", "path", dbPath, "err", err)
 	}
 	defer db.Close()
 
-	log.Printf("Database initialized at %s", dbPath)
+	slog.Info("Database initialized", "path", dbPath)
 
-	// Sync feeds from config to database
-	for _, feedCfg := range cfg.Feeds {
-		feed := &storage.Feed{
-			ID:       feedCfg.ID,
-			Name:     feedCfg.Name,
-			URL:      feedCfg.URL,
-			Category: feedCfg.Category,
-			Enabled:  feedCfg.Enabled,
-		}
-		if err := storage.UpsertFeed(db, feed); err != nil {
-			log.Printf("Warning: Failed to sync feed %s: %v", feedCfg.ID, err)
-		}
+	// Sync feeds from config to database, keeping their fetch history so feeds fetched
+	// shortly before a restart aren't polled again right away
+	syncFeedSettings(db, cfg)
+
+	// Share the config between the scheduler and handlers, and pick up hand edits
+	store := config.NewStore(configPath, cfg)
+
+	// Seed subscriptions from an OPML file; feeds already subscribed are skipped, so
+	// this is safe to run on every start
+	if opmlPath := os.Getenv("CALMNEWS_IMPORT_OPML"); opmlPath != "" {
+		importOPMLFile(db, store, opmlPath)
 	}
 
-	log.Printf("Synced %d feeds to database", len(cfg.Feeds))
+	go store.Watch(configReloadInterval, func(cfg *config.Config) {
+		applyLogLevel(cfg)
+		syncFeedSettings(db, cfg)
+	})
 
 	// Start background scheduler
-	refreshInterval := 10 // default
-	if len(cfg.Feeds) > 0 && cfg.Feeds[0].RefreshIntervalMinutes != nil {
-		refreshInterval = *cfg.Feeds[0].RefreshIntervalMinutes
-	}
-	feeds.StartScheduler(db, cfg, refreshInterval)
-	log.Printf("Started feed scheduler (refresh interval: %d minutes)", refreshInterval)
+	feeds.StartScheduler(db, store)
+	slog.Info("Started feed scheduler")
 
 	// Create web server
-	server := web.NewServer(db, cfg, configPath)
+	server, err := web.NewServer(db, store)
+	if err != nil {
+		fatal("Failed to load templates", "err", err)
+	}
 
 	// Setup HTTP routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", server.HandleIndex)
 	mux.HandleFunc("/settings", server.HandleSettings)
 	mux.HandleFunc("/settings/blocklist", server.HandleUpdateBlocklist)
+	mux.HandleFunc("/settings/allowlist", server.HandleUpdateAllowlist)
+	mux.HandleFunc("/settings/domain_blocklist", server.HandleUpdateDomainBlocklist)
 	mux.HandleFunc("/settings/feeds", server.HandleUpdateFeeds)
+	mux.HandleFunc("/settings/opml", server.HandleImportOPML)
+	mux.HandleFunc("/article", server.HandleArticle)
+	mux.HandleFunc("/article/open", server.HandleOpenArticle)
+	mux.HandleFunc("/article/share", server.HandleShareArticle)
 	mux.HandleFunc("/article/read", server.HandleMarkArticleRead)
+	mux.HandleFunc("/article/read-beacon", server.HandleReadBeacon)
+	mux.HandleFunc("/article/unread", server.HandleMarkArticleUnread)
+	mux.HandleFunc("/article/toggle-read", server.HandleToggleArticleRead)
+	mux.HandleFunc("/articles/mark-all-read", server.HandleMarkAllRead)
+	mux.HandleFunc("/articles/read-batch", server.HandleMarkArticlesRead)
+	mux.HandleFunc("/articles/purge", server.HandlePurgeArticles)
+	mux.HandleFunc("/feeds/refresh", server.HandleRefreshFeeds)
+	mux.HandleFunc("/feeds/mark-read", server.HandleMarkFeedRead)
 	mux.HandleFunc("/article/save", server.HandleToggleArticleSaved)
+	mux.HandleFunc("/article/queue", server.HandleToggleArticleQueued)
 	mux.HandleFunc("/article/trash", server.HandleTrashArticle)
+	mux.HandleFunc("/article/note", server.HandleSetArticleNote)
 	mux.HandleFunc("/settings/url_blocklist", server.HandleUpdateURLBlocklist)
 	mux.HandleFunc("/settings/theme", server.HandleUpdateTheme)
-	mux.HandleFunc("/static/", web.HandleStatic)
+	mux.HandleFunc("/api/articles", server.HandleAPIArticles)
+	mux.HandleFunc("/api/articles/next", server.HandleAPINextArticle)
+	mux.HandleFunc("/export.ndjson", server.HandleExportNDJSON)
+	mux.HandleFunc("/saved.xml", server.HandleSavedRSS)
+	mux.HandleFunc("/static/", server.HandleStatic)
+	mux.HandleFunc("/healthz", server.HandleHealthz)
+	mux.HandleFunc("/metrics", server.HandleMetrics)
 
-	// Get listen address from environment or use default
+	// Get listen address from environment, then config, then defaults
 	listenAddr := os.Getenv("CALMNEWS_LISTEN_ADDR")
 	if listenAddr == "" {
-		listenAddr = "0.0.0.0:8080"
+		listenAddr = cfg.Server.ListenAddr()
+	}
+
+	// Basic auth, when configured, guards every route uniformly
+	auth := cfg.Server.Auth
+	if auth.Enabled() {
+		if _, err := bcrypt.Cost([]byte(auth.PasswordHash)); err != nil {
+			fatal("server.auth.password_hash is not a valid bcrypt hash", "path", configPath, "err", err)
+		}
+		slog.Info("Basic auth enabled", "user", auth.Username)
 	}
 
 	// Create HTTP server
 	httpServer := &http.Server{
 		Addr:    listenAddr,
-		Handler: mux,
+		Handler: web.BasicAuth(mux, auth),
+	}
+
+	// HTTPS is enabled only when both the certificate and key are configured
+	certFile, keyFile := cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile
+	useTLS := certFile != "" && keyFile != ""
+	if (certFile != "") != (keyFile != "") {
+		fatal("Both server.tls_cert_file and server.tls_key_file must be set to enable HTTPS (only one is set)", "path", configPath)
+	}
+	if useTLS {
+		if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+			fatal("Failed to load TLS certificate/key", "err", err)
+		}
+	}
+
+	// Bind before starting the goroutine so address problems are reported clearly
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		fatal("Cannot listen (is another program already using this port? Change server.address/server.port in the config)",
+			"addr", listenAddr, "path", configPath, "err", err)
 	}
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Starting CalmNews server on http://%s", listenAddr)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+		var err error
+		if useTLS {
+			slog.Info("Starting CalmNews server", "url", "https://"+listenAddr)
+			err = httpServer.ServeTLS(listener, certFile, keyFile)
+		} else {
+			slog.Info("Starting CalmNews server", "url", "http://"+listenAddr)
+			err = httpServer.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			fatal("Server error", "err", err)
 		}
 	}()
 
@@ -118,16 +199,84 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := httpServer.Shutdown(ctx); err != nil {
-		log.Printf("Server forced to shutdown: %v", err)
+		slog.Warn("Server forced to shutdown", "err", err)
+	}
+
+	slog.Info("Server stopped")
+}
+
+// errNothingImported aborts the config update when an OPML import adds no feeds
+var errNothingImported = errors.New("no new feeds to import")
+
+// importOPMLFile adds the feeds in the OPML file at path to the database and config
+func importOPMLFile(db *sql.DB, store *config.Store, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fatal("Failed to read CALMNEWS_IMPORT_OPML file", "path", path, "err", err)
+	}
+	opmlFeeds, err := feeds.ParseOPML(data)
+	if err != nil {
+		fatal("Invalid OPML", "path", path, "err", err)
+	}
+
+	imported := 0
+	err = store.Update(func(cfg *config.Config) error {
+		imported = feeds.ImportOPMLFeeds(db, cfg, opmlFeeds)
+		if imported == 0 {
+			// Nothing new; don't rewrite config.yaml on every start
+			return errNothingImported
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errNothingImported) {
+		slog.Error("Error saving config after OPML import", "err", err)
+	}
+	slog.Info("Imported feeds from OPML", "path", path, "count", imported, "total", len(opmlFeeds))
+}
+
+// syncFeedSettings applies the feeds in a reloaded config to the database. Fetch
+// history is kept; feeds removed from the config are left in place.
+func syncFeedSettings(db *sql.DB, cfg *config.Config) {
+	for _, feedCfg := range cfg.Feeds {
+		feed := &storage.Feed{
+			ID:       feedCfg.ID,
+			Name:     feedCfg.Name,
+			URL:      feedCfg.URL,
+			Category: feedCfg.Category,
+			Enabled:  feedCfg.Enabled,
+		}
+		if err := storage.UpsertFeedSettings(db, feed); err != nil {
+			slog.Warn("Failed to sync feed", "feed_id", feedCfg.ID, "err", err)
+		}
 	}
+	slog.Info("Synced feeds to database", "count", len(cfg.Feeds))
+}
 
-	log.Println("Server stopped")
+// applyLogLevel sets the log level from CALMNEWS_LOG_LEVEL, or from the config's
+// log_level if the variable isn't set
+func applyLogLevel(cfg *config.Config) {
+	name := os.Getenv("CALMNEWS_LOG_LEVEL")
+	if name == "" {
+		name = cfg.LogLevel
+	}
+	level, err := config.ParseLogLevel(name)
+	if err != nil {
+		slog.Warn("Ignoring invalid log level", "level", name, "err", err)
+		return
+	}
+	logLevel.Set(level)
 }
 
+// fatal logs an error and exits. Unlike log.Fatalf it goes through slog, so the message
+// is shown at every log level.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -2,37 +2,418 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
 	"os"
 	"os/user"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // FeedConfig represents a single RSS/Atom feed configuration
 type FeedConfig struct {
-	ID                   string `yaml:"id"`
-	Name                 string `yaml:"name"`
-	URL                  string `yaml:"url"`
-	Category             string `yaml:"category"`
-	Enabled              bool   `yaml:"enabled"`
-	RefreshIntervalMinutes *int  `yaml:"refresh_interval_minutes,omitempty"`
+	ID                     string `yaml:"id"`
+	Name                   string `yaml:"name"`
+	URL                    string `yaml:"url"`
+	Category               string `yaml:"category"`
+	Enabled                bool   `yaml:"enabled"`
+	RefreshIntervalMinutes *int   `yaml:"refresh_interval_minutes,omitempty"`
+	// URLTemplate rewrites outbound article links, e.g. "https://archive.ph/newest/{url}".
+	// {url} is replaced with the query-escaped original article URL.
+	URLTemplate string `yaml:"url_template,omitempty"`
+	// Blocklist holds extra phrases blocked only for this feed, on top of the global blocklist
+	Blocklist []string `yaml:"blocklist,omitempty"`
+	// NeverExpire keeps this feed's articles past the retention window, like saved articles
+	NeverExpire bool `yaml:"never_expire,omitempty"`
+	// FullText fetches each new article's web page in the background and replaces its
+	// content with the extracted article body. Off by default since it makes a request per article.
+	FullText bool `yaml:"full_text,omitempty"`
+	// IgnoreScheduleHints polls the feed on refresh_interval_minutes alone, ignoring the
+	// feed's own <ttl>, <skipHours> and <skipDays>
+	IgnoreScheduleHints bool `yaml:"ignore_schedule_hints,omitempty"`
+	// DateFormat is a Go time layout, e.g. "02/01/2006 15:04", for item dates the
+	// feed parser doesn't recognize. Items whose dates still can't be parsed get the
+	// fetch time.
+	DateFormat string `yaml:"date_format,omitempty"`
+	// FollowNextPages is how many extra pages to fetch by following the feed's
+	// rel="next" link (RFC 5005), for feeds that only show their latest items.
+	// Zero, the default, reads only the first page; at most MaxFollowNextPages.
+	FollowNextPages int `yaml:"follow_next_pages,omitempty"`
+}
+
+// MaxFollowNextPages caps follow_next_pages so a feed whose pages never end can't
+// turn a fetch into a crawl
+const MaxFollowNextPages = 10
+
+// OutboundURL returns the link to open for an article of this feed: articleURL
+// rewritten through URLTemplate, or articleURL itself if the feed has no template.
+// It returns an error if the template has no {url} placeholder or doesn't produce an
+// absolute http(s) URL.
+func (f FeedConfig) OutboundURL(articleURL string) (string, error) {
+	if f.URLTemplate == "" {
+		return articleURL, nil
+	}
+	if !strings.Contains(f.URLTemplate, "{url}") {
+		return "", fmt.Errorf("url_template %q has no {url} placeholder", f.URLTemplate)
+	}
+	link := strings.ReplaceAll(f.URLTemplate, "{url}", url.QueryEscape(articleURL))
+	if !isHTTPURL(link) {
+		return "", fmt.Errorf("url_template %q does not produce an absolute http(s) URL", f.URLTemplate)
+	}
+	return link, nil
 }
 
 // UIConfig represents UI-related settings
 type UIConfig struct {
+	// ItemsPerPage is how many articles a page shows. Unset means DefaultItemsPerPage;
+	// values outside MinItemsPerPage-MaxItemsPerPage are clamped (see PageSize).
 	ItemsPerPage      int    `yaml:"items_per_page"`
 	DefaultView       string `yaml:"default_view"`
 	ShowFilteredCount bool   `yaml:"show_filtered_count"`
 	Theme             string `yaml:"theme,omitempty"`
+	// ViewWindowHours overrides how far back the latest, today and week views reach,
+	// e.g. {latest: 24}. Missing or non-positive entries keep the built-in windows.
+	ViewWindowHours map[string]int `yaml:"view_window_hours,omitempty"`
+	// AssetsDir, if set, is a directory whose templates/ and static/ files override the
+	// built-in ones of the same name. Templates are re-read on every page load.
+	AssetsDir string `yaml:"assets_dir,omitempty"`
+	// TimeDisplay shows article and fetch times as "relative" ("3 hours ago", the
+	// default) or "absolute" (formatted with TimeFormat)
+	TimeDisplay string `yaml:"time_display,omitempty"`
+	// TimeFormat is the Go time layout for absolute times, e.g. "02.01.2006 15:04".
+	// Empty means DefaultTimeFormat.
+	TimeFormat string `yaml:"time_format,omitempty"`
+	// JustNowSeconds is how recent a relative time must be to show as "just now".
+	// Zero means DefaultJustNowSeconds.
+	JustNowSeconds int `yaml:"just_now_seconds,omitempty"`
+	// Timezone is the IANA time zone name, e.g. "America/Los_Angeles", that decides
+	// where the today view and date ranges start and end and in which times are shown.
+	// Empty means the server's local time zone.
+	Timezone string `yaml:"timezone,omitempty"`
+}
+
+// Location returns the configured time zone, or the server's local time zone if none
+// is set or the name is unknown
+func (u UIConfig) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// Values for ui.time_display
+const (
+	TimeDisplayRelative = "relative"
+	TimeDisplayAbsolute = "absolute"
+)
+
+// Defaults for ui.time_format and ui.just_now_seconds
+const (
+	DefaultTimeFormat     = "Jan 2, 2006 15:04"
+	DefaultJustNowSeconds = 60
+)
+
+// TimeLayout returns the configured layout for absolute times, or DefaultTimeFormat
+func (u UIConfig) TimeLayout() string {
+	if u.TimeFormat == "" {
+		return DefaultTimeFormat
+	}
+	return u.TimeFormat
+}
+
+// JustNow returns how recent a relative time must be to show as "just now"
+func (u UIConfig) JustNow() time.Duration {
+	if u.JustNowSeconds <= 0 {
+		return DefaultJustNowSeconds * time.Second
+	}
+	return time.Duration(u.JustNowSeconds) * time.Second
+}
+
+// Bounds and default for ui.items_per_page and the per_page query parameter
+const (
+	DefaultItemsPerPage = 50
+	MinItemsPerPage     = 5
+	MaxItemsPerPage     = 200
+)
+
+// PageSize returns how many articles to show per page: requested if positive,
+// otherwise the configured ItemsPerPage or the default, clamped to the allowed range
+func (u UIConfig) PageSize(requested int) int {
+	size := requested
+	if size <= 0 {
+		size = u.ItemsPerPage
+	}
+	if size <= 0 {
+		size = DefaultItemsPerPage
+	}
+	return min(max(size, MinItemsPerPage), MaxItemsPerPage)
+}
+
+// validThemes are the accepted values for ui.theme. Empty and "light" both mean the
+// default light theme; "auto" follows the browser's prefers-color-scheme.
+var validThemes = map[string]bool{
+	"": true, "light": true, "dark": true, "auto": true,
+	"terminal": true, "military": true, "industrial": true, "space": true,
+}
+
+// ValidTheme reports whether theme is a known ui.theme value
+func ValidTheme(theme string) bool {
+	return validThemes[theme]
+}
+
+// ViewWindow returns the configured time window for a view, or zero if the view
+// should use its built-in window
+func (u UIConfig) ViewWindow(view string) time.Duration {
+	switch view {
+	case "latest", "today", "week":
+		if hours := u.ViewWindowHours[view]; hours > 0 {
+			return time.Duration(hours) * time.Hour
+		}
+	}
+	return 0
+}
+
+// ArticlesConfig represents article lifecycle settings
+type ArticlesConfig struct {
+	// CatchUpDays marks unread, unsaved articles older than this many days as read.
+	// Zero disables the policy.
+	CatchUpDays int `yaml:"catch_up_days,omitempty"`
+	// DedupByTitle skips fetched articles whose title matches any stored article.
+	// When false, duplicates are detected only by article ID (feed URL + GUID).
+	// Superseded by DedupScope, which takes precedence when set.
+	DedupByTitle bool `yaml:"dedup_by_title,omitempty"`
+	// DedupScope chooses which stored articles a fetched article's title is checked
+	// against: "none", "per-feed" (same feed only) or "global" (all feeds). Unset means
+	// "global" if DedupByTitle is true and "none" otherwise.
+	DedupScope string `yaml:"dedup_scope,omitempty"`
+	// DedupByContent skips fetched articles whose normalized title and summary match an
+	// article fetched within ContentDedupDays, catching reposts that come back under a
+	// new GUID. Off by default since it also drops legitimately recurring posts.
+	DedupByContent bool `yaml:"dedup_by_content,omitempty"`
+	// ContentDedupDays is how far back DedupByContent looks. Zero uses DefaultContentDedupDays.
+	ContentDedupDays int `yaml:"content_dedup_days,omitempty"`
+	// SummaryMaxChars truncates stored summaries at a word boundary. Zero means no truncation.
+	SummaryMaxChars int `yaml:"summary_max_chars,omitempty"`
+	// RetentionHours is how long unsaved articles are kept after being fetched.
+	// Unset means DefaultRetentionHours; zero disables cleanup entirely.
+	RetentionHours *int `yaml:"retention_hours,omitempty"`
+	// KeepDisabledFeeds exempts the articles of disabled feeds from retention cleanup,
+	// like those of never_expire feeds, for as long as the feed stays disabled
+	KeepDisabledFeeds bool `yaml:"keep_disabled_feeds,omitempty"`
+	// FuzzyDedup hides near-identical articles across feeds (similar titles or the same
+	// link), showing only the earliest copy. Articles are still stored.
+	FuzzyDedup bool `yaml:"fuzzy_dedup,omitempty"`
+	// FuzzyDedupThreshold is the title similarity (0-1) treated as a duplicate.
+	// Zero uses the default of 0.8.
+	FuzzyDedupThreshold float64 `yaml:"fuzzy_dedup_threshold,omitempty"`
+	// MaxArticlesPerFeed keeps only this many of each feed's unsaved articles, the most
+	// recently published, after every fetch. Zero means unlimited.
+	MaxArticlesPerFeed int `yaml:"max_articles_per_feed,omitempty"`
+	// TrackingParams are the query parameters removed from article links. An entry ending
+	// in "*" matches by prefix, e.g. "utm_*". Empty means DefaultTrackingParams.
+	TrackingParams []string `yaml:"tracking_params,omitempty"`
+	// KeepTrackingParams stores article links exactly as the feed gives them
+	KeepTrackingParams bool `yaml:"keep_tracking_params,omitempty"`
+	// Languages, if set, keeps only fetched articles in these languages (e.g. ["en"]).
+	// Articles whose language is unknown are always kept.
+	Languages []string `yaml:"languages,omitempty"`
+}
+
+// Title dedup scopes for ArticlesConfig.DedupScope
+const (
+	DedupScopeNone    = "none"
+	DedupScopePerFeed = "per-feed"
+	DedupScopeGlobal  = "global"
+)
+
+// TitleDedupScope returns the effective title dedup scope, honoring the older
+// dedup_by_title setting when dedup_scope isn't set
+func (a ArticlesConfig) TitleDedupScope() string {
+	if a.DedupScope != "" {
+		return a.DedupScope
+	}
+	if a.DedupByTitle {
+		return DedupScopeGlobal
+	}
+	return DedupScopeNone
+}
+
+// DefaultContentDedupDays is how far back content dedup looks when not configured
+const DefaultContentDedupDays = 7
+
+// ContentDedupWindow returns how far back content dedup looks for a matching article
+func (a ArticlesConfig) ContentDedupWindow() time.Duration {
+	days := a.ContentDedupDays
+	if days <= 0 {
+		days = DefaultContentDedupDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// LanguageCodes returns the configured languages as normalized codes, e.g. "en-US" as "en"
+func (a ArticlesConfig) LanguageCodes() []string {
+	var codes []string
+	for _, lang := range a.Languages {
+		if code := NormalizeLanguage(lang); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// DefaultTrackingParams are the analytics and ad-click parameters stripped from article
+// links when tracking_params isn't configured. None of them change the page's content.
+var DefaultTrackingParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid", "yclid",
+	"mc_cid", "mc_eid", "igshid", "_hsenc", "_hsmi", "mkt_tok", "vero_id",
+}
+
+// TrackingParamsOrDefault returns the query parameters to strip from article links,
+// or nil if links should be kept as they are
+func (a ArticlesConfig) TrackingParamsOrDefault() []string {
+	if a.KeepTrackingParams {
+		return nil
+	}
+	if len(a.TrackingParams) == 0 {
+		return DefaultTrackingParams
+	}
+	return a.TrackingParams
+}
+
+// DefaultRetentionHours is the article retention used when none is configured
+const DefaultRetentionHours = 72
+
+// RetentionHoursOrDefault returns the configured retention, or DefaultRetentionHours if unset
+func (a ArticlesConfig) RetentionHoursOrDefault() int {
+	if a.RetentionHours == nil {
+		return DefaultRetentionHours
+	}
+	return *a.RetentionHours
+}
+
+// SchedulerConfig represents feed fetching settings
+type SchedulerConfig struct {
+	// StartupDelaySeconds postpones the first fetches after startup so the app can
+	// finish starting and serve its first pages. Zero starts fetching right away.
+	StartupDelaySeconds int `yaml:"startup_delay_seconds,omitempty"`
+	// RefreshOnStart fetches every enabled, unmuted feed once at startup, even if it was
+	// fetched recently. By default a feed waits for the rest of its interval.
+	RefreshOnStart bool `yaml:"refresh_on_start,omitempty"`
+	// CategoryIntervalMinutes sets the refresh interval for every feed in a category,
+	// e.g. {news: 30}. A feed's own refresh_interval_minutes takes precedence.
+	CategoryIntervalMinutes map[string]int `yaml:"category_interval_minutes,omitempty"`
+	// MaxFetchesPerMinute caps how many scheduled feed fetches start in any minute. Feeds
+	// over the limit wait, most overdue first. Zero means unlimited.
+	MaxFetchesPerMinute int `yaml:"max_fetches_per_minute,omitempty"`
+	// SaveFailedFeeds writes each feed response that can't be parsed to the
+	// failed-feeds directory in the data dir, keeping the last few per feed, for debugging
+	SaveFailedFeeds bool `yaml:"save_failed_feeds,omitempty"`
+}
+
+// StartupDelay returns how long to wait before the first fetches after startup
+func (s SchedulerConfig) StartupDelay() time.Duration {
+	return time.Duration(max(s.StartupDelaySeconds, 0)) * time.Second
+}
+
+// ServerConfig represents HTTP server settings
+type ServerConfig struct {
+	Address string `yaml:"address,omitempty"` // bind address, default "0.0.0.0"
+	Port    int    `yaml:"port,omitempty"`    // default 8080
+	// TLSCertFile and TLSKeyFile enable HTTPS when both are set
+	TLSCertFile string `yaml:"tls_cert_file,omitempty"`
+	TLSKeyFile  string `yaml:"tls_key_file,omitempty"`
+	// Auth enables HTTP Basic auth for the web UI when a username is set
+	Auth AuthConfig `yaml:"auth,omitempty"`
+}
+
+// AuthConfig represents optional HTTP Basic auth credentials
+type AuthConfig struct {
+	Username     string `yaml:"username,omitempty"`
+	PasswordHash string `yaml:"password_hash,omitempty"` // bcrypt hash of the password
+	ExemptStatic bool   `yaml:"exempt_static,omitempty"` // serve /static/ without auth
+	// ProtectHealth requires auth for /healthz and /metrics too; by default they stay
+	// open so monitors can reach them
+	ProtectHealth bool `yaml:"protect_health,omitempty"`
+}
+
+// Enabled reports whether Basic auth is configured
+func (a AuthConfig) Enabled() bool {
+	return a.Username != ""
+}
+
+// Default HTTP bind address and port
+const (
+	DefaultServerAddress = "0.0.0.0"
+	DefaultServerPort    = 8080
+)
+
+// ListenAddr returns the host:port to listen on, filling in defaults for unset values
+func (s ServerConfig) ListenAddr() string {
+	address := s.Address
+	if address == "" {
+		address = DefaultServerAddress
+	}
+	port := s.Port
+	if port == 0 {
+		port = DefaultServerPort
+	}
+	return net.JoinHostPort(address, strconv.Itoa(port))
 }
 
 // Config represents the complete application configuration
 type Config struct {
-	Feeds       []FeedConfig `yaml:"feeds"`
-	Blocklist   []string     `yaml:"blocklist"`
-	URLBlocklist []string    `yaml:"url_blocklist,omitempty"`
-	UI          UIConfig     `yaml:"ui"`
+	Feeds     []FeedConfig `yaml:"feeds"`
+	Blocklist []string     `yaml:"blocklist"`
+	// BlocklistMatchContent also matches blocklist phrases against article content
+	// (as plain text), not just the title and summary. Off by default since content can be large.
+	BlocklistMatchContent bool     `yaml:"blocklist_match_content,omitempty"`
+	URLBlocklist          []string `yaml:"url_blocklist,omitempty"`
+	Allowlist             []string `yaml:"allowlist,omitempty"`
+	DomainBlocklist       []string `yaml:"domain_blocklist,omitempty"`
+	// RemoteBlocklistURLs are shared blocklists, one phrase per line, fetched hourly and
+	// applied along with Blocklist. Their phrases aren't copied into Blocklist.
+	RemoteBlocklistURLs []string        `yaml:"remote_blocklist_urls,omitempty"`
+	UI                  UIConfig        `yaml:"ui"`
+	Articles            ArticlesConfig  `yaml:"articles,omitempty"`
+	Server              ServerConfig    `yaml:"server,omitempty"`
+	Scheduler           SchedulerConfig `yaml:"scheduler,omitempty"`
+	// LogLevel is the minimum level logged: debug, info, warn or error. Defaults to info.
+	LogLevel string `yaml:"log_level,omitempty"`
+}
+
+// ParseLogLevel converts a log_level name to a slog level. An empty name means info.
+func ParseLogLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q, must be debug, info, warn or error", name)
+}
+
+// FeedBlocklists returns the per-feed blocklists keyed by feed ID, omitting feeds without one
+func (c *Config) FeedBlocklists() map[string][]string {
+	lists := make(map[string][]string)
+	for _, f := range c.Feeds {
+		if len(f.Blocklist) > 0 {
+			lists[f.ID] = f.Blocklist
+		}
+	}
+	return lists
 }
 
 // DataDir returns the path to the CalmNews data directory
@@ -42,7 +423,7 @@ func DataDir() (string, error) {
 	if dataDir := os.Getenv("CALMNEWS_DATA_DIR"); dataDir != "" {
 		return dataDir, nil
 	}
-	
+
 	// Default to home directory
 	usr, err := user.Current()
 	if err != nil {
@@ -75,16 +456,49 @@ func LoadConfig(path string) (*Config, error) {
 	return &cfg, nil
 }
 
-// SaveConfig saves configuration to a YAML file
+// SaveConfig saves configuration to a YAML file. The file is written to a temporary file
+// in the same directory and renamed into place, so a crash mid-write never leaves a
+// truncated config. The previous version is kept as path + ".bak".
 func SaveConfig(path string, cfg *Config) error {
 	data, err := yaml.Marshal(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp config file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	// Clean up the temp file on any failure; after the rename this is a no-op
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close config file: %w", err)
+	}
+	// CreateTemp uses 0600
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return fmt.Errorf("failed to set config file permissions: %w", err)
+	}
+
+	// Keep the previous version; a failed backup shouldn't block saving
+	if old, err := os.ReadFile(path); err == nil {
+		if err := os.WriteFile(path+".bak", old, 0644); err != nil {
+			slog.Warn("Error backing up config file", "err", err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
 
 	return nil
 }
@@ -95,19 +509,19 @@ func DefaultConfig() *Config {
 	return &Config{
 		Feeds: []FeedConfig{
 			{
-				ID:                   "hackernews",
-				Name:                 "Hacker News",
-				URL:                  "https://hnrss.org/frontpage",
-				Category:             "tech",
-				Enabled:              true,
+				ID:                     "hackernews",
+				Name:                   "Hacker News",
+				URL:                    "https://hnrss.org/frontpage",
+				Category:               "tech",
+				Enabled:                true,
 				RefreshIntervalMinutes: &refreshInterval,
 			},
 			{
-				ID:                   "lobsters",
-				Name:                 "Lobsters",
-				URL:                  "https://lobste.rs/rss",
-				Category:             "tech",
-				Enabled:              true,
+				ID:                     "lobsters",
+				Name:                   "Lobsters",
+				URL:                    "https://lobste.rs/rss",
+				Category:               "tech",
+				Enabled:                true,
 				RefreshIntervalMinutes: &refreshInterval,
 			},
 		},
@@ -116,10 +530,9 @@ func DefaultConfig() *Config {
 			"trump",
 		},
 		UI: UIConfig{
-			ItemsPerPage:      50,
+			ItemsPerPage:      DefaultItemsPerPage,
 			DefaultView:       "latest",
 			ShowFilteredCount: true,
 		},
 	}
 }
-
@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestPageSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured int
+		requested  int
+		want       int
+	}{
+		{"unset", 0, 0, DefaultItemsPerPage},
+		{"configured", 30, 0, 30},
+		{"configured negative", -10, 0, DefaultItemsPerPage},
+		{"configured too small", 1, 0, MinItemsPerPage},
+		{"configured huge", 1000000, 0, MaxItemsPerPage},
+		{"requested overrides configured", 30, 100, 100},
+		{"requested zero uses configured", 30, 0, 30},
+		{"requested negative uses configured", 30, -1, 30},
+		{"requested too small", 30, 1, MinItemsPerPage},
+		{"requested huge", 30, 1 << 30, MaxItemsPerPage},
+		{"requested at the bounds", 30, MaxItemsPerPage, MaxItemsPerPage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ui := UIConfig{ItemsPerPage: tt.configured}
+			if got := ui.PageSize(tt.requested); got != tt.want {
+				t.Errorf("PageSize(%d) with items_per_page %d = %d, want %d", tt.requested, tt.configured, got, tt.want)
+			}
+		})
+	}
+}
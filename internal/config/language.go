@@ -0,0 +1,19 @@
+package config
+
+import "strings"
+
+// NormalizeLanguage reduces a language tag such as "en-US" or "EN_gb" to its lowercase
+// primary subtag ("en"). It returns "" for an empty or malformed tag.
+func NormalizeLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	if len(primary) < 2 || len(primary) > 3 {
+		return ""
+	}
+	for _, r := range primary {
+		if r < 'a' || r > 'z' {
+			return ""
+		}
+	}
+	return primary
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// slugLetters spells out letters that don't decompose into a base letter and an accent
+var slugLetters = strings.NewReplacer("ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "đ", "d", "ł", "l", "þ", "th")
+
+// Slugify turns s into a lowercase, URL-safe ID made of ASCII letters, digits and
+// hyphens, e.g. "Café Society News" becomes "cafe-society-news". Accents are dropped;
+// other characters become hyphens. It returns "feed" if nothing usable is left.
+func Slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+	// Decompose so accented letters become a base letter plus a droppable mark
+	for _, r := range norm.NFD.String(slugLetters.Replace(strings.ToLower(s))) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			hyphen = false
+		case b.Len() > 0 && !hyphen:
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "feed"
+	}
+	return slug
+}
+
+// UniqueID returns base if it isn't taken, otherwise base with the first free numeric
+// suffix: base-2, base-3 and so on
+func UniqueID(base string, taken func(id string) bool) string {
+	if !taken(base) {
+		return base
+	}
+	for i := 2; ; i++ {
+		id := base + "-" + strconv.Itoa(i)
+		if !taken(id) {
+			return id
+		}
+	}
+}
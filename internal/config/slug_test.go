@@ -0,0 +1,51 @@
+package config
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Hacker News", "hacker-news"},
+		{"  Leading and trailing  ", "leading-and-trailing"},
+		{"Many   spaces\tand\ttabs", "many-spaces-and-tabs"},
+		{"Café Society News", "cafe-society-news"},
+		{"Ñandú Über Åland", "nandu-uber-aland"},
+		{"Straße & Smørrebrød", "strasse-smorrebrod"},
+		{"Encyclopædia Œuvre", "encyclopaedia-oeuvre"},
+		{"Łódź Daily", "lodz-daily"},
+		{"Cafe\u0301 decomposed", "cafe-decomposed"},
+		{"The Verge: Tech!", "the-verge-tech"},
+		{"C++ / Go -- weekly", "c-go-weekly"},
+		{"example.com", "example-com"},
+		{"BBC News 24", "bbc-news-24"},
+		{"--already-slugged--", "already-slugged"},
+		{"Новости", "feed"},
+		{"!!!", "feed"},
+		{"", "feed"},
+	}
+	for _, tt := range tests {
+		if got := Slugify(tt.in); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueID(t *testing.T) {
+	taken := map[string]bool{"news": true, "news-2": true, "news-4": true, "tech-2": true}
+	isTaken := func(id string) bool { return taken[id] }
+
+	tests := []struct {
+		base, want string
+	}{
+		{"sports", "sports"}, // free IDs are used as is
+		{"news", "news-3"},   // first free suffix, not one past the highest
+		{"tech", "tech"},     // a taken suffixed ID doesn't block the base
+		{"news-2", "news-2-2"},
+	}
+	for _, tt := range tests {
+		if got := UniqueID(tt.base, isTaken); got != tt.want {
+			t.Errorf("UniqueID(%q) = %q, want %q", tt.base, got, tt.want)
+		}
+	}
+}
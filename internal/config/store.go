@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store holds the current configuration and the file it is saved to. It is safe for
+// concurrent use: Get returns a snapshot that must not be modified, and changes go
+// through Update, which edits a copy, saves it and then publishes it.
+type Store struct {
+	path    string
+	current atomic.Pointer[Config]
+
+	mu      sync.Mutex // serializes Update and Reload
+	modTime time.Time  // config file modification time when last loaded or saved
+}
+
+// NewStore returns a Store holding cfg, which was loaded from or saved to path
+func NewStore(path string, cfg *Config) *Store {
+	s := &Store{path: path}
+	s.current.Store(cfg)
+	if info, err := os.Stat(path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return s
+}
+
+// Get returns the current configuration. Callers must treat it as read-only.
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Path returns the path of the config file
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Update applies fn to a copy of the current configuration, saves it and makes it
+// current. If fn returns an error or the changed configuration fails Validate, nothing
+// is saved and the error is returned.
+func (s *Store) Update(fn func(cfg *Config) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg := s.Get().Clone()
+	if err := fn(cfg); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if err := SaveConfig(s.path, cfg); err != nil {
+		return err
+	}
+	// Our own write must not look like a hand edit to the watcher
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+
+	s.current.Store(cfg)
+	return nil
+}
+
+// Reload re-reads the config file if it changed since it was last loaded or saved.
+// It returns the new configuration, or nil if the file is unchanged. If the file
+// can't be read, parsed or validated, the current configuration is kept and the
+// error returned.
+func (s *Store) Reload() (*Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat config file: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return nil, nil
+	}
+
+	// Don't retry the same broken file on every check
+	s.modTime = info.ModTime()
+
+	cfg, err := LoadConfig(s.path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file:\n%w", err)
+	}
+
+	s.current.Store(cfg)
+	return cfg, nil
+}
+
+// Watch checks the config file for changes every interval and reloads it, calling
+// onReload with the new configuration after each successful reload. It never returns.
+func (s *Store) Watch(interval time.Duration, onReload func(cfg *Config)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cfg, err := s.Reload()
+		if err != nil {
+			slog.Error("Error reloading config, keeping the previous one", "path", s.path, "err", err)
+			continue
+		}
+		if cfg != nil {
+			slog.Info("Reloaded config", "path", s.path)
+			if onReload != nil {
+				onReload(cfg)
+			}
+		}
+	}
+}
+
+// Clone returns a copy of c whose slices can be modified without affecting c
+func (c *Config) Clone() *Config {
+	clone := *c
+	clone.Feeds = slices.Clone(c.Feeds)
+	for i := range clone.Feeds {
+		clone.Feeds[i].Blocklist = slices.Clone(c.Feeds[i].Blocklist)
+	}
+	clone.Blocklist = slices.Clone(c.Blocklist)
+	clone.URLBlocklist = slices.Clone(c.URLBlocklist)
+	clone.Allowlist = slices.Clone(c.Allowlist)
+	clone.DomainBlocklist = slices.Clone(c.DomainBlocklist)
+	clone.RemoteBlocklistURLs = slices.Clone(c.RemoteBlocklistURLs)
+	clone.UI.ViewWindowHours = maps.Clone(c.UI.ViewWindowHours)
+	clone.Scheduler.CategoryIntervalMinutes = maps.Clone(c.Scheduler.CategoryIntervalMinutes)
+	clone.Articles.TrackingParams = slices.Clone(c.Articles.TrackingParams)
+	clone.Articles.Languages = slices.Clone(c.Articles.Languages)
+	return &clone
+}
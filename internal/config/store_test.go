@@ -0,0 +1,56 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCloneRemoteBlocklistURLs(t *testing.T) {
+	cfg := &Config{RemoteBlocklistURLs: []string{"https://lists.example.com/a.txt"}}
+	clone := cfg.Clone()
+	clone.RemoteBlocklistURLs[0] = "https://lists.example.com/b.txt"
+	clone.RemoteBlocklistURLs = append(clone.RemoteBlocklistURLs, "https://lists.example.com/c.txt")
+
+	if len(cfg.RemoteBlocklistURLs) != 1 || cfg.RemoteBlocklistURLs[0] != "https://lists.example.com/a.txt" {
+		t.Errorf("editing the clone changed the original: %q", cfg.RemoteBlocklistURLs)
+	}
+}
+
+func TestCloneCategoryIntervals(t *testing.T) {
+	cfg := &Config{Scheduler: SchedulerConfig{CategoryIntervalMinutes: map[string]int{"news": 30}}}
+	clone := cfg.Clone()
+	clone.Scheduler.CategoryIntervalMinutes["news"] = 5
+	clone.Scheduler.CategoryIntervalMinutes["tech"] = 10
+
+	if len(cfg.Scheduler.CategoryIntervalMinutes) != 1 || cfg.Scheduler.CategoryIntervalMinutes["news"] != 30 {
+		t.Errorf("editing the clone changed the original: %v", cfg.Scheduler.CategoryIntervalMinutes)
+	}
+}
+
+func TestUpdateRejectsInvalidConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	cfg := validConfig()
+	if err := SaveConfig(path, cfg); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	store := NewStore(path, cfg)
+
+	err := store.Update(func(c *Config) error {
+		c.Feeds = append(c.Feeds, FeedConfig{ID: "a", Name: "Copy", URL: "https://example.com/copy.xml", Enabled: true})
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "duplicate feed id") {
+		t.Fatalf("Update = %v, want a duplicate feed id error", err)
+	}
+	if n := len(store.Get().Feeds); n != 2 {
+		t.Errorf("current config has %d feeds, want the 2 from before the update", n)
+	}
+	saved, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(saved.Feeds) != 2 {
+		t.Errorf("saved config has %d feeds, want the 2 from before the update", len(saved.Feeds))
+	}
+}
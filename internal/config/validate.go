@@ -0,0 +1,157 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// validViews are the accepted values for ui.default_view
+var validViews = map[string]bool{"latest": true, "today": true, "week": true, "saved": true, "history": true, "queue": true}
+
+// Validate checks the configuration for mistakes that would otherwise show up as
+// confusing runtime behavior. It returns a single error listing every problem found,
+// or nil if the configuration is valid.
+func (c *Config) Validate() error {
+	var errs []error
+
+	seen := make(map[string]bool)
+	for i, f := range c.Feeds {
+		// Identify the feed by ID when possible so the message is easy to act on
+		label := fmt.Sprintf("feeds[%d]", i)
+		if f.ID != "" {
+			label = fmt.Sprintf("feed %q", f.ID)
+		}
+
+		if strings.TrimSpace(f.ID) == "" {
+			errs = append(errs, fmt.Errorf("%s: id is required", label))
+		} else if seen[f.ID] {
+			errs = append(errs, fmt.Errorf("%s: duplicate feed id", label))
+		}
+		seen[f.ID] = true
+
+		if strings.TrimSpace(f.Name) == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", label))
+		}
+		if strings.TrimSpace(f.URL) == "" {
+			errs = append(errs, fmt.Errorf("%s: url is required", label))
+		} else if !isHTTPURL(f.URL) {
+			errs = append(errs, fmt.Errorf("%s: url %q is not an absolute http(s) URL", label, f.URL))
+		}
+		if _, err := f.OutboundURL("https://example.com/article"); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", label, err))
+		}
+		if f.RefreshIntervalMinutes != nil && *f.RefreshIntervalMinutes <= 0 {
+			errs = append(errs, fmt.Errorf("%s: refresh_interval_minutes must be positive", label))
+		}
+		if f.DateFormat != "" && !validDateLayout(f.DateFormat) {
+			errs = append(errs, fmt.Errorf("%s: date_format %q must be a Go time layout with a year, month and day, such as \"2006-01-02 15:04\"", label, f.DateFormat))
+		}
+		if f.FollowNextPages < 0 || f.FollowNextPages > MaxFollowNextPages {
+			errs = append(errs, fmt.Errorf("%s: follow_next_pages must be between 0 and %d", label, MaxFollowNextPages))
+		}
+	}
+
+	for _, u := range c.RemoteBlocklistURLs {
+		if !isHTTPURL(u) {
+			errs = append(errs, fmt.Errorf("remote_blocklist_urls: %q is not an absolute http(s) URL", u))
+		}
+	}
+
+	if c.UI.DefaultView != "" && !validViews[c.UI.DefaultView] {
+		errs = append(errs, fmt.Errorf("ui.default_view %q must be one of latest, today, week, saved, history or queue", c.UI.DefaultView))
+	}
+	if !ValidTheme(c.UI.Theme) {
+		errs = append(errs, fmt.Errorf("ui.theme %q must be one of light, dark, auto, terminal, military, industrial or space", c.UI.Theme))
+	}
+	switch c.UI.TimeDisplay {
+	case "", TimeDisplayRelative, TimeDisplayAbsolute:
+	default:
+		errs = append(errs, fmt.Errorf("ui.time_display %q must be relative or absolute", c.UI.TimeDisplay))
+	}
+	if c.UI.Timezone != "" {
+		if _, err := time.LoadLocation(c.UI.Timezone); err != nil {
+			errs = append(errs, fmt.Errorf("ui.timezone %q is not a known IANA time zone such as \"Europe/Berlin\"", c.UI.Timezone))
+		}
+	}
+	if c.UI.JustNowSeconds < 0 {
+		errs = append(errs, fmt.Errorf("ui.just_now_seconds must not be negative, got %d", c.UI.JustNowSeconds))
+	}
+	if c.UI.ItemsPerPage < 0 {
+		errs = append(errs, fmt.Errorf("ui.items_per_page must not be negative, got %d", c.UI.ItemsPerPage))
+	}
+
+	if c.UI.AssetsDir != "" {
+		if info, err := os.Stat(c.UI.AssetsDir); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("ui.assets_dir %q is not a directory", c.UI.AssetsDir))
+		}
+	}
+
+	if c.Articles.MaxArticlesPerFeed < 0 {
+		errs = append(errs, fmt.Errorf("articles.max_articles_per_feed must not be negative, got %d", c.Articles.MaxArticlesPerFeed))
+	}
+	for _, lang := range c.Articles.Languages {
+		if NormalizeLanguage(lang) == "" {
+			errs = append(errs, fmt.Errorf("articles.languages: %q is not a language code such as \"en\"", lang))
+		}
+	}
+	switch c.Articles.DedupScope {
+	case "", DedupScopeNone, DedupScopePerFeed, DedupScopeGlobal:
+	default:
+		errs = append(errs, fmt.Errorf("articles.dedup_scope must be none, per-feed or global, got %q", c.Articles.DedupScope))
+	}
+	if c.Articles.ContentDedupDays < 0 {
+		errs = append(errs, fmt.Errorf("articles.content_dedup_days must not be negative, got %d", c.Articles.ContentDedupDays))
+	}
+	if t := c.Articles.FuzzyDedupThreshold; t < 0 || t > 1 {
+		errs = append(errs, fmt.Errorf("articles.fuzzy_dedup_threshold must be between 0 and 1, got %g", t))
+	}
+
+	if c.Scheduler.StartupDelaySeconds < 0 {
+		errs = append(errs, fmt.Errorf("scheduler.startup_delay_seconds must not be negative, got %d", c.Scheduler.StartupDelaySeconds))
+	}
+	if c.Scheduler.MaxFetchesPerMinute < 0 {
+		errs = append(errs, fmt.Errorf("scheduler.max_fetches_per_minute must not be negative, got %d", c.Scheduler.MaxFetchesPerMinute))
+	}
+	for _, category := range slices.Sorted(maps.Keys(c.Scheduler.CategoryIntervalMinutes)) {
+		if minutes := c.Scheduler.CategoryIntervalMinutes[category]; minutes <= 0 {
+			errs = append(errs, fmt.Errorf("scheduler.category_interval_minutes: %q must be positive, got %d", category, minutes))
+		}
+	}
+
+	if _, err := ParseLogLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %w", err))
+	}
+
+	if c.Server.Auth.Enabled() && c.Server.Auth.PasswordHash == "" {
+		errs = append(errs, errors.New("server.auth.password_hash is required when server.auth.username is set"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validDateLayout reports whether layout is a Go time layout that reads back the date
+// it writes, which fails for layouts missing the year, month or day
+func validDateLayout(layout string) bool {
+	ref := time.Date(2021, time.November, 23, 0, 0, 0, 0, time.UTC)
+	t, err := time.Parse(layout, ref.Format(layout))
+	if err != nil {
+		return false
+	}
+	y, m, d := t.Date()
+	return y == ref.Year() && m == ref.Month() && d == ref.Day()
+}
+
+// isHTTPURL reports whether raw is an absolute http(s) URL with a host
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
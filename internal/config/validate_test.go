@@ -0,0 +1,95 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// validConfig returns a small config that passes Validate
+func validConfig() *Config {
+	return &Config{
+		Feeds: []FeedConfig{
+			{ID: "a", Name: "A", URL: "https://example.com/a.xml", Category: "news", Enabled: true},
+			{ID: "b", Name: "B", URL: "http://example.org/b.xml", Category: "tech", Enabled: true, URLTemplate: "https://archive.ph/newest/{url}"},
+		},
+		UI: UIConfig{ItemsPerPage: DefaultItemsPerPage, DefaultView: "latest"},
+	}
+}
+
+func TestValidateAcceptsValidConfig(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("default config: %v", err)
+	}
+
+	// Unset values fall back to defaults
+	cfg := validConfig()
+	cfg.UI.ItemsPerPage = 0
+	cfg.UI.DefaultView = ""
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unset items_per_page and default_view: %v", err)
+	}
+}
+
+func TestValidateFailureModes(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"duplicate feed id", func(c *Config) { c.Feeds[1].ID = "a" }, `feed "a": duplicate feed id`},
+		{"empty id", func(c *Config) { c.Feeds[1].ID = "" }, "feeds[1]: id is required"},
+		{"blank id", func(c *Config) { c.Feeds[0].ID = "  " }, "id is required"},
+		{"empty name", func(c *Config) { c.Feeds[0].Name = "" }, `feed "a": name is required`},
+		{"empty url", func(c *Config) { c.Feeds[0].URL = "" }, `feed "a": url is required`},
+		{"relative url", func(c *Config) { c.Feeds[0].URL = "/feed.xml" }, `url "/feed.xml" is not an absolute http(s) URL`},
+		{"url without scheme", func(c *Config) { c.Feeds[0].URL = "example.com/feed.xml" }, "is not an absolute http(s) URL"},
+		{"non-http url", func(c *Config) { c.Feeds[0].URL = "ftp://example.com/feed.xml" }, "is not an absolute http(s) URL"},
+		{"url without host", func(c *Config) { c.Feeds[0].URL = "https:///feed.xml" }, "is not an absolute http(s) URL"},
+		{"unparseable url", func(c *Config) { c.Feeds[0].URL = "https://exa mple.com/%zz" }, "is not an absolute http(s) URL"},
+		{"invalid remote blocklist url", func(c *Config) { c.RemoteBlocklistURLs = []string{"lists.txt"} }, `remote_blocklist_urls: "lists.txt"`},
+		{"invalid default_view", func(c *Config) { c.UI.DefaultView = "popular" }, `ui.default_view "popular" must be one of`},
+		{"negative items_per_page", func(c *Config) { c.UI.ItemsPerPage = -1 }, "ui.items_per_page must not be negative, got -1"},
+		{"url_template without placeholder", func(c *Config) { c.Feeds[0].URLTemplate = "https://archive.ph/newest/" }, `feed "a": url_template "https://archive.ph/newest/" has no {url} placeholder`},
+		{"relative url_template", func(c *Config) { c.Feeds[0].URLTemplate = "/read?u={url}" }, "does not produce an absolute http(s) URL"},
+		{"non-positive refresh interval", func(c *Config) { c.Feeds[0].RefreshIntervalMinutes = new(int) }, "refresh_interval_minutes must be positive"},
+		{"invalid log level", func(c *Config) { c.LogLevel = "loud" }, `unknown log level "loud"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Validate returned nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate = %q, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateListsEveryProblem(t *testing.T) {
+	cfg := validConfig()
+	cfg.Feeds[1].ID = "a"
+	cfg.Feeds[1].URL = ""
+	cfg.UI.DefaultView = "popular"
+	cfg.UI.ItemsPerPage = -5
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate returned nil")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != 4 {
+		t.Errorf("got %d problems, want 4:\n%v", len(lines), err)
+	}
+	for _, want := range []string{"duplicate feed id", "url is required", "ui.default_view", "ui.items_per_page"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package dedup detects near-identical articles, such as the same story picked up by
+// an aggregator and by the original site, so that only one copy is shown.
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"calmnews/internal/storage"
+)
+
+// DefaultThreshold is the title similarity at or above which two articles are
+// considered duplicates when no threshold is configured
+const DefaultThreshold = 0.8
+
+// titleSeparators split a headline from a trailing site name, as in "Title | Site"
+var titleSeparators = []string{" | ", " - ", " – ", " — ", " :: ", " · "}
+
+// maxSuffixWords is the longest trailing segment that is treated as a site name
+const maxSuffixWords = 5
+
+// NormalizeTitle reduces a title to lowercase words without punctuation, dropping a
+// short trailing site name such as " | Example Blog" or " - The Site"
+func NormalizeTitle(title string) string {
+	title = strings.TrimSpace(title)
+	for _, sep := range titleSeparators {
+		i := strings.LastIndex(title, sep)
+		if i <= 0 {
+			continue
+		}
+		suffix := title[i+len(sep):]
+		if n := len(strings.Fields(suffix)); n > 0 && n <= maxSuffixWords && len(strings.Fields(title[:i])) >= n {
+			title = title[:i]
+			break
+		}
+	}
+
+	return normalizeWords(title)
+}
+
+// normalizeWords lowercases s and reduces it to its words, dropping punctuation
+func normalizeWords(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// ContentHash fingerprints an article by its normalized title and summary, so a
+// lightly edited repost that comes back under a new GUID still gets the same hash.
+// Changes to case, punctuation, whitespace or a trailing site name don't change it.
+func ContentHash(title, summary string) string {
+	hash := sha256.Sum256([]byte(NormalizeTitle(title) + "\n" + normalizeWords(summary)))
+	return hex.EncodeToString(hash[:])
+}
+
+// CanonicalURL reduces a link to a comparison key: scheme, "www." prefix, fragment,
+// trailing slash and utm_* tracking parameters are ignored. It returns "" for links
+// that can't be parsed.
+func CanonicalURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+
+	key := host + path
+	if encoded := query.Encode(); encoded != "" {
+		key += "?" + encoded
+	}
+	return key
+}
+
+// Similarity returns the Jaccard similarity of the word sets of two normalized titles,
+// from 0 (no words in common) to 1 (same words)
+func Similarity(a, b string) float64 {
+	wordsA := strings.Fields(a)
+	wordsB := strings.Fields(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+
+	set := make(map[string]bool, len(wordsA))
+	for _, w := range wordsA {
+		set[w] = true
+	}
+	union := len(set)
+	shared := 0
+	seenB := make(map[string]bool, len(wordsB))
+	for _, w := range wordsB {
+		if seenB[w] {
+			continue
+		}
+		seenB[w] = true
+		if set[w] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	return float64(shared) / float64(union)
+}
+
+// Articles removes near-duplicate articles and returns the remaining ones in their
+// original order, along with the number removed. Two articles from different feeds are
+// duplicates if their links are the same after canonicalization or their normalized
+// titles are at least threshold similar; articles from the same feed are never merged.
+// Of each group of duplicates the earliest published article is kept. A threshold of
+// 0 or less uses DefaultThreshold.
+func Articles(articles []*storage.Article, threshold float64) ([]*storage.Article, int) {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+
+	// Visit articles oldest first so the first copy of a story wins
+	order := make([]int, len(articles))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return articles[order[i]].PublishedAt.Before(articles[order[j]].PublishedAt)
+	})
+
+	type keptTitle struct {
+		title  string
+		feedID string
+	}
+	var keptTitles []keptTitle
+	urlFeeds := make(map[string]map[string]bool) // canonical link -> IDs of feeds that had it
+	duplicate := make([]bool, len(articles))
+	removed := 0
+
+	for _, i := range order {
+		a := articles[i]
+		urlKey := CanonicalURL(a.URL)
+		title := NormalizeTitle(a.Title)
+
+		isDup := false
+		if urlKey != "" {
+			for feedID := range urlFeeds[urlKey] {
+				if feedID != a.FeedID {
+					isDup = true
+					break
+				}
+			}
+		}
+		for _, t := range keptTitles {
+			if isDup {
+				break
+			}
+			isDup = t.feedID != a.FeedID && title != "" && (t.title == title || Similarity(t.title, title) >= threshold)
+		}
+
+		// Remember the link even for duplicates, since it belongs to the same story
+		if urlKey != "" {
+			if urlFeeds[urlKey] == nil {
+				urlFeeds[urlKey] = make(map[string]bool)
+			}
+			urlFeeds[urlKey][a.FeedID] = true
+		}
+		if isDup {
+			duplicate[i] = true
+			removed++
+			continue
+		}
+		keptTitles = append(keptTitles, keptTitle{title, a.FeedID})
+	}
+
+	if removed == 0 {
+		return articles, 0
+	}
+
+	result := make([]*storage.Article, 0, len(articles)-removed)
+	for i, a := range articles {
+		if !duplicate[i] {
+			result = append(result, a)
+		}
+	}
+	return result, removed
+}
@@ -0,0 +1,127 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"calmnews/internal/storage"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Big News | Example Blog", "big news"},
+		{"Big News - The Site", "big news"},
+		{"Big News — The Site", "big news"},
+		{"  Hello, World!  ", "hello world"},
+		{"Rust 1.80 released", "rust 1 80 released"},
+		// Segments too long to be a site name are kept
+		{"Site - a very long trailing part of the headline", "site a very long trailing part of the headline"},
+		// A suffix longer than the rest of the title is not a site name
+		{"AI - The Daily Example", "ai the daily example"},
+		// Nothing before the separator, so there is no site name to drop
+		{" | Example Blog", "example blog"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTitle(tt.title); got != tt.want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"https://example.com/post", "example.com/post"},
+		{"https://example.com/post/", "example.com/post"},
+		{"http://www.example.com/post", "example.com/post"},
+		{"HTTPS://WWW.Example.COM/post", "example.com/post"},
+		{"https://example.com/post?utm_source=hn&utm_Medium=social", "example.com/post"},
+		{"https://example.com/post/?id=2&utm_campaign=launch#comments", "example.com/post?id=2"},
+		{"https://example.com/Post", "example.com/Post"}, // paths are case-sensitive
+		{"/relative/post", ""},
+		{"not a url", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := CanonicalURL(tt.raw); got != tt.want {
+			t.Errorf("CanonicalURL(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestArticles(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	article := func(id, feedID, title, link string, minutes int) *storage.Article {
+		return &storage.Article{ID: id, FeedID: feedID, Title: title, URL: link, PublishedAt: base.Add(time.Duration(minutes) * time.Minute)}
+	}
+
+	tests := []struct {
+		name     string
+		articles []*storage.Article
+		want     []string // IDs kept, in the original order
+	}{
+		{
+			"earliest copy is kept",
+			[]*storage.Article{
+				article("late", "aggregator", "New compiler release | Aggregator", "https://news.example.com/item/1", 30),
+				article("early", "blog", "New compiler release - Blog", "https://blog.example.com/release", 0),
+			},
+			[]string{"early"},
+		},
+		{
+			"same link from another feed",
+			[]*storage.Article{
+				article("a", "blog", "Release notes", "https://blog.example.com/release", 0),
+				article("b", "aggregator", "Something else entirely", "http://www.blog.example.com/release/?utm_source=feed", 10),
+			},
+			[]string{"a"},
+		},
+		{
+			"similar titles in the same feed",
+			[]*storage.Article{
+				article("mon", "digest", "Daily digest: world news", "https://digest.example.com/mon", 0),
+				article("tue", "digest", "Daily digest: world news", "https://digest.example.com/tue", 10),
+			},
+			[]string{"mon", "tue"},
+		},
+		{
+			"same link in the same feed",
+			[]*storage.Article{
+				article("first", "blog", "Part one", "https://blog.example.com/series", 0),
+				article("second", "blog", "Part two", "https://blog.example.com/series", 10),
+			},
+			[]string{"first", "second"},
+		},
+		{
+			"different stories",
+			[]*storage.Article{
+				article("a", "blog", "Gardening in spring", "https://blog.example.com/garden", 0),
+				article("b", "news", "Election results announced", "https://news.example.com/election", 10),
+			},
+			[]string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, removed := Articles(tt.articles, 0)
+			var ids []string
+			for _, a := range kept {
+				ids = append(ids, a.ID)
+			}
+			if len(ids) != len(tt.want) || removed != len(tt.articles)-len(tt.want) {
+				t.Fatalf("kept %v (removed %d), want %v", ids, removed, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Errorf("kept %v, want %v", ids, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package feeds
+
+import (
+	"log/slog"
+	"time"
+
+	"calmnews/internal/config"
+	"calmnews/internal/filter"
+)
+
+// remoteBlocklistInterval is how long a fetched remote blocklist is used before it is
+// fetched again. Failed fetches are retried on the next maintenance tick.
+const remoteBlocklistInterval = time.Hour
+
+// refreshRemoteBlocklists fetches the configured remote blocklists that are due. A
+// list that can't be fetched keeps its last good copy.
+func refreshRemoteBlocklists(cfg *config.Config) {
+	now := time.Now()
+	for _, list := range filter.RemoteBlocklists(cfg.RemoteBlocklistURLs) {
+		if !list.FetchedAt.IsZero() && now.Sub(list.FetchedAt) < remoteBlocklistInterval {
+			continue
+		}
+		result, err := FetchFeed(list.URL)
+		if err != nil {
+			slog.Warn("Error fetching remote blocklist", "url", list.URL, "err", err)
+			filter.SetRemoteBlocklistError(list.URL, err)
+			continue
+		}
+		phrases, err := filter.ParseBlocklist(result.Data)
+		if err != nil {
+			slog.Warn("Error parsing remote blocklist", "url", list.URL, "err", err)
+			filter.SetRemoteBlocklistError(list.URL, err)
+			continue
+		}
+		filter.SetRemoteBlocklist(list.URL, phrases, now)
+		slog.Info("Fetched remote blocklist", "url", list.URL, "count", len(phrases))
+	}
+}
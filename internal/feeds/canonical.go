@@ -0,0 +1,61 @@
+package feeds
+
+import (
+	"net/url"
+	"strings"
+)
+
+// stripTrackingParams removes the query parameters matching any of params from a URL,
+// keeping the order and encoding of the rest. An entry ending in "*" matches by prefix.
+// Names are compared case-insensitively. Anything but a parseable http(s) URL is returned unchanged.
+func stripTrackingParams(rawURL string, params []string) string {
+	if len(params) == 0 || !strings.Contains(rawURL, "?") {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return rawURL
+	}
+
+	var kept []string
+	removed := false
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(pair, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		if isTrackingParam(name, params) {
+			removed = true
+		} else {
+			kept = append(kept, pair)
+		}
+	}
+	// Leave clean links byte-for-byte as the feed gave them
+	if !removed {
+		return rawURL
+	}
+
+	u.RawQuery = strings.Join(kept, "&")
+	// Drop the "?" entirely when nothing is left
+	u.ForceQuery = false
+	return u.String()
+}
+
+// isTrackingParam reports whether a query parameter name matches one of params
+func isTrackingParam(name string, params []string) bool {
+	name = strings.ToLower(name)
+	for _, p := range params {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if prefix != "" && strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if p != "" && name == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package feeds
+
+import (
+	"testing"
+
+	"calmnews/internal/config"
+)
+
+func TestStripTrackingParams(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{
+			"newsletter link",
+			"https://www.example.com/2024/05/story.html?utm_source=newsletter&utm_medium=email&utm_campaign=daily_brief&utm_content=top",
+			"https://www.example.com/2024/05/story.html",
+		},
+		{
+			"facebook share",
+			"https://example.com/article?fbclid=IwAR2xYz_abc-123",
+			"https://example.com/article",
+		},
+		{
+			"content params kept in order",
+			"https://shop.example.com/item?id=42&utm_source=rss&page=2&gclid=Cj0KCQ&lang=en",
+			"https://shop.example.com/item?id=42&page=2&lang=en",
+		},
+		{
+			"fragment kept",
+			"https://example.com/live?utm_medium=social#update-3",
+			"https://example.com/live#update-3",
+		},
+		{
+			"encoding of kept params untouched",
+			"https://example.com/search?q=caf%C3%A9+society&utm_source=rss&tag=a%2Fb",
+			"https://example.com/search?q=caf%C3%A9+society&tag=a%2Fb",
+		},
+		{
+			"upper-case tracking names",
+			"https://example.com/a?UTM_Source=Twitter&Ref=home",
+			"https://example.com/a?Ref=home",
+		},
+		{
+			"encoded tracking name",
+			"https://example.com/a?utm%5Fsource=x&id=1",
+			"https://example.com/a?id=1",
+		},
+		{
+			"mailchimp and hubspot",
+			"https://example.com/post?mc_cid=abc123&mc_eid=def456&_hsenc=p2ANqtz&_hsmi=123",
+			"https://example.com/post",
+		},
+		{
+			"empty pairs dropped with the tracking params",
+			"https://example.com/a?&utm_source=x&&id=1&",
+			"https://example.com/a?id=1",
+		},
+		{
+			"tracking param without a value",
+			"https://example.com/a?utm_source&id=1",
+			"https://example.com/a?id=1",
+		},
+		{
+			"prefix only matches entries ending in *",
+			"https://example.com/a?gclid_info=keep&utmost=keep",
+			"https://example.com/a?gclid_info=keep&utmost=keep",
+		},
+		{
+			"clean link unchanged",
+			"https://example.com/a?b=1&a=2",
+			"https://example.com/a?b=1&a=2",
+		},
+		{
+			"no query",
+			"https://example.com/a",
+			"https://example.com/a",
+		},
+		{
+			"relative link unchanged",
+			"/story?utm_source=rss",
+			"/story?utm_source=rss",
+		},
+		{
+			"non-http link unchanged",
+			"mailto:news@example.com?subject=hi&utm_source=rss",
+			"mailto:news@example.com?subject=hi&utm_source=rss",
+		},
+		{
+			"unparseable link unchanged",
+			"https://exa mple.com/%zz?utm_source=rss",
+			"https://exa mple.com/%zz?utm_source=rss",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripTrackingParams(tt.in, config.DefaultTrackingParams); got != tt.want {
+				t.Errorf("stripTrackingParams(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripTrackingParamsConfigured(t *testing.T) {
+	link := "https://example.com/a?utm_source=rss&ref=home&id=1"
+	if got, want := stripTrackingParams(link, []string{"ref"}), "https://example.com/a?utm_source=rss&id=1"; got != want {
+		t.Errorf("custom list: %q, want %q", got, want)
+	}
+	if got := stripTrackingParams(link, nil); got != link {
+		t.Errorf("no params: %q, want the link unchanged", got)
+	}
+
+	keep := config.ArticlesConfig{KeepTrackingParams: true, TrackingParams: []string{"ref"}}
+	if got := stripTrackingParams(link, keep.TrackingParamsOrDefault()); got != link {
+		t.Errorf("keep_tracking_params: %q, want the link unchanged", got)
+	}
+}
@@ -0,0 +1,62 @@
+package feeds
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// xmlEncodingRe matches the encoding attribute of a leading XML declaration
+var xmlEncodingRe = regexp.MustCompile(`^(\s*<\?xml[^>]*?\sencoding\s*=\s*)["']([^"']*)["']`)
+
+// detectCharset returns the charset label declared by the XML prolog or, failing that,
+// by the Content-Type header. It returns an empty string if neither declares one.
+func detectCharset(data []byte, contentType string) string {
+	// Only the start of the document can hold the XML declaration
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if m := xmlEncodingRe.FindSubmatch(head); m != nil {
+		return strings.TrimSpace(string(m[2]))
+	}
+
+	if contentType != "" {
+		if _, params, err := mime.ParseMediaType(contentType); err == nil {
+			return strings.TrimSpace(params["charset"])
+		}
+	}
+
+	return ""
+}
+
+// toUTF8 transcodes feed data to UTF-8 based on its declared charset.
+// If the charset is missing, unknown or already UTF-8, data is returned unchanged.
+func toUTF8(data []byte, contentType string) []byte {
+	label := detectCharset(data, contentType)
+	if label == "" {
+		return data
+	}
+
+	_, name := charset.Lookup(label)
+	if name == "" || name == "utf-8" {
+		return data
+	}
+
+	reader, err := charset.NewReaderLabel(label, bytes.NewReader(data))
+	if err != nil {
+		return data
+	}
+	converted, err := io.ReadAll(reader)
+	if err != nil {
+		return data
+	}
+
+	// The body is now UTF-8, so the XML declaration must say so too,
+	// otherwise the parser would decode it a second time
+	return xmlEncodingRe.ReplaceAll(converted, []byte(`${1}"UTF-8"`))
+}
@@ -1,6 +1,8 @@
 package feeds
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,15 +12,64 @@ import (
 const (
 	maxResponseSize = 10 * 1024 * 1024 // 10MB
 	httpTimeout     = 30 * time.Second
+	maxRedirects    = 5
 )
 
-// FetchFeed fetches an RSS/Atom feed from the given URL
-func FetchFeed(url string) ([]byte, error) {
+// ErrRedirectLoop is returned when a feed redirects back to a URL it already visited
+var ErrRedirectLoop = errors.New("redirect loop")
+
+// StatusError is returned when a feed responds with an HTTP status other than 200 OK
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// FetchResult holds a fetched feed body and where it was finally served from
+type FetchResult struct {
+	Data        []byte
+	ContentType string
+	// StatusCode is the HTTP status of the final response
+	StatusCode int
+	// Duration is how long the request took, including redirects and reading the body
+	Duration time.Duration
+	// FinalURL is the URL the body was served from after following redirects
+	FinalURL string
+	// MovedTo is the new URL when every redirect followed was permanent (301/308),
+	// meaning the subscription should be updated. It is empty otherwise.
+	MovedTo string
+}
+
+// FetchFeed fetches an RSS/Atom feed from the given URL, following at most maxRedirects redirects
+func FetchFeed(url string) (*FetchResult, error) {
+	return fetchFeedContext(context.Background(), url)
+}
+
+// fetchFeedContext is FetchFeed with a context that can cancel the request early
+func fetchFeedContext(ctx context.Context, url string) (*FetchResult, error) {
+	start := time.Now()
+	permanent := true
 	client := &http.Client{
 		Timeout: httpTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			for _, prev := range via {
+				if prev.URL.String() == req.URL.String() {
+					return fmt.Errorf("%w at %s", ErrRedirectLoop, req.URL)
+				}
+			}
+			if code := req.Response.StatusCode; code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+				permanent = false
+			}
+			return nil
+		},
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -32,7 +83,7 @@ func FetchFeed(url string) ([]byte, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	// Limit response size
@@ -42,6 +93,15 @@ func FetchFeed(url string) ([]byte, error) {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	return data, nil
+	result := &FetchResult{
+		Data:        data,
+		ContentType: resp.Header.Get("Content-Type"),
+		StatusCode:  resp.StatusCode,
+		Duration:    time.Since(start),
+		FinalURL:    resp.Request.URL.String(),
+	}
+	if result.FinalURL != url && permanent {
+		result.MovedTo = result.FinalURL
+	}
+	return result, nil
 }
-
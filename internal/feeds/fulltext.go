@@ -0,0 +1,107 @@
+package feeds
+
+import (
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+
+	"calmnews/internal/config"
+	"calmnews/internal/storage"
+)
+
+const (
+	// fullTextDelay is the pause between article page fetches, so full-text feeds don't
+	// hammer the sites they link to
+	fullTextDelay = 2 * time.Second
+	// fullTextQueueSize bounds how many articles can wait for extraction
+	fullTextQueueSize = 200
+	// fullTextPerFetch is how many pending articles are queued after each feed fetch
+	fullTextPerFetch = 20
+)
+
+// FullTextExtractor extracts article bodies for feeds with full_text enabled.
+// Replace it before StartScheduler to use a different extraction method.
+var FullTextExtractor ContentExtractor = ReadabilityExtractor{}
+
+// fullTextJob is an article waiting for its page to be fetched and extracted
+type fullTextJob struct {
+	articleID string
+	url       string
+}
+
+var (
+	fullTextQueue = make(chan fullTextJob, fullTextQueueSize)
+	// fullTextQueued holds the IDs in fullTextQueue, so repeated fetches don't queue an article twice
+	fullTextQueued   = make(map[string]bool)
+	fullTextQueuedMu sync.Mutex
+)
+
+// fullTextEnabled reports whether full-text extraction is enabled for a feed
+func fullTextEnabled(cfg *config.Config, feedID string) bool {
+	for _, feedCfg := range cfg.Feeds {
+		if feedCfg.ID == feedID {
+			return feedCfg.FullText
+		}
+	}
+	return false
+}
+
+// queueFullText queues a feed's newest articles that haven't been extracted yet. If the
+// queue is full, the rest are picked up after a later fetch.
+func queueFullText(db *sql.DB, feed *storage.Feed) {
+	articles, err := storage.ListArticlesPendingFullText(db, feed.ID, fullTextPerFetch)
+	if err != nil {
+		slog.Error("Error listing articles for full text", "feed_id", feed.ID, "err", err)
+		return
+	}
+
+	fullTextQueuedMu.Lock()
+	defer fullTextQueuedMu.Unlock()
+	for _, a := range articles {
+		if fullTextQueued[a.ID] {
+			continue
+		}
+		select {
+		case fullTextQueue <- fullTextJob{articleID: a.ID, url: a.URL}:
+			fullTextQueued[a.ID] = true
+		default:
+			slog.Warn("Full-text queue is full, deferring remaining articles", "feed_id", feed.ID)
+			return
+		}
+	}
+}
+
+// runFullTextWorker extracts queued articles one at a time, pausing fullTextDelay between pages
+func runFullTextWorker(db *sql.DB) {
+	for job := range fullTextQueue {
+		extractFullText(db, job)
+
+		fullTextQueuedMu.Lock()
+		delete(fullTextQueued, job.articleID)
+		fullTextQueuedMu.Unlock()
+
+		time.Sleep(fullTextDelay)
+	}
+}
+
+// extractFullText fetches an article's page and stores the extracted body as its content.
+// Failures are recorded so the article isn't retried on every fetch.
+func extractFullText(db *sql.DB, job fullTextJob) {
+	result, err := FetchFeed(job.url)
+	var content string
+	if err == nil {
+		content, err = FullTextExtractor.Extract(result.Data, result.ContentType, result.FinalURL)
+	}
+	if err != nil {
+		slog.Warn("Error extracting full text", "article_id", job.articleID, "url", job.url, "err", err)
+		if err := storage.MarkFullTextFailed(db, job.articleID); err != nil {
+			slog.Error("Error recording full-text failure", "article_id", job.articleID, "err", err)
+		}
+		return
+	}
+
+	if err := storage.SetArticleFullText(db, job.articleID, content); err != nil {
+		slog.Error("Error storing full text", "article_id", job.articleID, "url", job.url, "err", err)
+	}
+}
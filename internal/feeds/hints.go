@@ -0,0 +1,76 @@
+package feeds
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/rss"
+)
+
+// ScheduleHints are a feed's own hints about how often it wants to be polled. Only RSS
+// has them (<ttl>, <skipHours> and <skipDays>); they are empty for Atom and JSON feeds.
+type ScheduleHints struct {
+	// TTLMinutes is how long the feed may be cached before refreshing; zero if unset
+	TTLMinutes int
+	// SkipHours are hours of the day (0-23, UTC) during which the feed shouldn't be fetched
+	SkipHours []int
+	// SkipDays are days of the week (in UTC) on which the feed shouldn't be fetched
+	SkipDays []time.Weekday
+}
+
+// hintTranslator is the default RSS translator, additionally recording the channel's
+// schedule hints and next-page link, which gofeed's universal Feed doesn't carry
+type hintTranslator struct {
+	gofeed.DefaultRSSTranslator
+	hints   ScheduleHints
+	nextURL string
+}
+
+// Translate implements gofeed.Translator
+func (t *hintTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	if rssFeed, ok := feed.(*rss.Feed); ok {
+		t.hints = rssScheduleHints(rssFeed)
+		t.nextURL = rssNextLink(rssFeed)
+	}
+	return t.DefaultRSSTranslator.Translate(feed)
+}
+
+// rssScheduleHints reads the schedule hints of an RSS channel, ignoring malformed values
+func rssScheduleHints(feed *rss.Feed) ScheduleHints {
+	var hints ScheduleHints
+	if ttl, err := strconv.Atoi(strings.TrimSpace(feed.TTL)); err == nil && ttl > 0 {
+		hints.TTLMinutes = ttl
+	}
+	for _, h := range feed.SkipHours {
+		hour, err := strconv.Atoi(strings.TrimSpace(h))
+		if err != nil || hour < 0 || hour > 24 {
+			continue
+		}
+		// Some feeds number the hours 1-24, with 24 meaning midnight
+		hour %= 24
+		if !slices.Contains(hints.SkipHours, hour) {
+			hints.SkipHours = append(hints.SkipHours, hour)
+		}
+	}
+	for _, d := range feed.SkipDays {
+		day, ok := parseWeekday(d)
+		if ok && !slices.Contains(hints.SkipDays, day) {
+			hints.SkipDays = append(hints.SkipDays, day)
+		}
+	}
+	return hints
+}
+
+// parseWeekday parses an English day name such as "Saturday", ignoring case
+func parseWeekday(s string) (time.Weekday, bool) {
+	s = strings.TrimSpace(s)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(s, day.String()) {
+			return day, true
+		}
+	}
+	return 0, false
+}
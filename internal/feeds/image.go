@@ -0,0 +1,105 @@
+package feeds
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+	"golang.org/x/net/html"
+)
+
+// articleImageURL picks the best thumbnail for an item: a media:thumbnail, then the
+// image gofeed found (iTunes image, media:content, image enclosure or inline <img>
+// for RSS), then an image enclosure, then the first <img> in the content. Relative
+// URLs are resolved against the item link. It returns "" if there is no usable image.
+func articleImageURL(item *gofeed.Item) string {
+	candidates := mediaThumbnails(item.Extensions)
+	if item.Image != nil {
+		candidates = append(candidates, item.Image.URL)
+	}
+	for _, enc := range item.Enclosures {
+		if strings.HasPrefix(enc.Type, "image/") {
+			candidates = append(candidates, enc.URL)
+		}
+	}
+	candidates = append(candidates, firstImageSrc(item.Content), firstImageSrc(item.Description))
+
+	for _, c := range candidates {
+		if u := resolveImageURL(item.Link, c); u != "" {
+			return u
+		}
+	}
+	return ""
+}
+
+// mediaThumbnails returns the media:thumbnail URLs of an item, including thumbnails
+// nested in media:group and media:content elements
+func mediaThumbnails(extensions ext.Extensions) []string {
+	media, ok := extensions["media"]
+	if !ok {
+		return nil
+	}
+
+	var urls []string
+	var collect func(elems map[string][]ext.Extension)
+	collect = func(elems map[string][]ext.Extension) {
+		for _, t := range elems["thumbnail"] {
+			if u := t.Attrs["url"]; u != "" {
+				urls = append(urls, u)
+			}
+		}
+		for _, name := range []string{"group", "content"} {
+			for _, e := range elems[name] {
+				collect(e.Children)
+			}
+		}
+	}
+	collect(media)
+	return urls
+}
+
+// firstImageSrc returns the src of the first <img> in an HTML fragment, or ""
+func firstImageSrc(s string) string {
+	if !strings.Contains(s, "<img") && !strings.Contains(s, "<IMG") {
+		return ""
+	}
+
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if token.Data != "img" {
+				continue
+			}
+			for _, attr := range token.Attr {
+				if attr.Key == "src" {
+					return attr.Val
+				}
+			}
+		}
+	}
+}
+
+// resolveImageURL makes raw absolute relative to base and returns it if it is an
+// http(s) URL, or "" otherwise (data: URIs, javascript:, unparsable values)
+func resolveImageURL(base, raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	if b, err := url.Parse(base); err == nil {
+		u = b.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,123 @@
+package feeds
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mmcdole/gofeed"
+
+	"calmnews/internal/config"
+)
+
+// itemLanguage returns the language of a feed item: the one the item declares, else the
+// feed's, else one detected from the title and summary. It returns "" if unknown.
+func itemLanguage(feed *gofeed.Feed, item *gofeed.Item, summary string) string {
+	if item.DublinCoreExt != nil {
+		for _, lang := range item.DublinCoreExt.Language {
+			if code := config.NormalizeLanguage(lang); code != "" {
+				return code
+			}
+		}
+	}
+	if code := config.NormalizeLanguage(feed.Language); code != "" {
+		return code
+	}
+	return detectLanguage(item.Title + " " + summary)
+}
+
+// scriptLanguages maps writing systems used by essentially one language to that language
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	code   string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Arabic, "ar"},
+	{unicode.Thai, "th"},
+}
+
+// stopwords are very common words that identify a language in a short text
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "for", "with", "on", "are", "this", "from", "was", "it", "be", "by", "how", "what", "why"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "en", "del", "por", "para", "con", "una", "es", "se", "al", "como", "más"},
+	"fr": {"le", "la", "les", "des", "de", "et", "est", "une", "du", "que", "pour", "dans", "sur", "pas", "avec", "au", "aux", "qui"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "ein", "eine", "den", "von", "zu", "auf", "für", "im", "dem", "sich", "auch"},
+	"it": {"il", "la", "di", "che", "e", "per", "un", "una", "del", "della", "non", "con", "sono", "gli", "nel", "alla", "più"},
+	"pt": {"o", "a", "os", "as", "de", "que", "e", "do", "da", "em", "um", "uma", "para", "com", "não", "por", "dos", "mais"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "op", "dat", "met", "voor", "zijn", "ook", "bij", "naar", "wordt"},
+	"ru": {"и", "в", "не", "на", "что", "с", "как", "по", "это", "из", "для", "от", "о", "к", "его", "уже"},
+	"uk": {"і", "та", "в", "не", "на", "що", "з", "як", "це", "для", "від", "до", "його", "вже", "є"},
+}
+
+// stopwordIndex maps each stopword to the languages it belongs to
+var stopwordIndex = func() map[string][]string {
+	index := make(map[string][]string)
+	for code, words := range stopwords {
+		for _, w := range words {
+			index[w] = append(index[w], code)
+		}
+	}
+	return index
+}()
+
+// minStopwordHits is the fewest stopwords a text must contain before its language is guessed
+const minStopwordHits = 2
+
+// detectLanguage guesses the language of a short text from its script, or for Latin and
+// Cyrillic text from stopword counts. It returns "" unless one language clearly wins.
+func detectLanguage(text string) string {
+	letters := 0
+	scriptCounts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, sl := range scriptLanguages {
+			if unicode.Is(sl.script, r) {
+				scriptCounts[sl.code]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	// Japanese mixes kana with Han characters; any kana means Japanese
+	if scriptCounts["ja"] > 0 && scriptCounts["ja"]+scriptCounts["zh"] > letters/2 {
+		return "ja"
+	}
+	for code, n := range scriptCounts {
+		if n > letters/2 {
+			return code
+		}
+	}
+
+	scores := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, w := range words {
+		for _, code := range stopwordIndex[w] {
+			scores[code]++
+		}
+	}
+
+	best, bestScore, tied := "", 0, false
+	for code, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = code, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if bestScore < minStopwordHits || tied {
+		return ""
+	}
+	return best
+}
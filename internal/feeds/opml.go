@@ -0,0 +1,153 @@
+package feeds
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"calmnews/internal/config"
+	"calmnews/internal/storage"
+)
+
+// defaultOPMLCategory is used for OPML feeds that aren't nested in a folder and have no category attribute
+const defaultOPMLCategory = "general"
+
+// OPMLFeed is a feed subscription read from an OPML document
+type OPMLFeed struct {
+	Title    string
+	URL      string
+	Category string
+}
+
+type opmlDocument struct {
+	Body struct {
+		Outlines []opmlOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr"`
+	XMLURL   string        `xml:"xmlUrl,attr"`
+	Category string        `xml:"category,attr"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// ParseOPML extracts feed subscriptions from an OPML document.
+// Outlines without an xmlUrl are treated as folders whose title becomes the category of the feeds nested in them.
+func ParseOPML(data []byte) ([]OPMLFeed, error) {
+	var doc opmlDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OPML: %w", err)
+	}
+
+	var feeds []OPMLFeed
+	collectOPMLFeeds(doc.Body.Outlines, "", &feeds)
+	return feeds, nil
+}
+
+func collectOPMLFeeds(outlines []opmlOutline, category string, feeds *[]OPMLFeed) {
+	for _, o := range outlines {
+		title := strings.TrimSpace(o.Title)
+		if title == "" {
+			title = strings.TrimSpace(o.Text)
+		}
+
+		feedURL := strings.TrimSpace(o.XMLURL)
+		if feedURL == "" {
+			// Folder outline: its title is the category for its children
+			collectOPMLFeeds(o.Outlines, title, feeds)
+			continue
+		}
+
+		feedCategory := category
+		if feedCategory == "" {
+			feedCategory = opmlCategoryAttr(o.Category)
+		}
+		if feedCategory == "" {
+			feedCategory = defaultOPMLCategory
+		}
+		if title == "" {
+			// Fall back to the host name so the feed is still recognizable
+			if u, err := url.Parse(feedURL); err == nil && u.Host != "" {
+				title = u.Host
+			} else {
+				title = feedURL
+			}
+		}
+
+		*feeds = append(*feeds, OPMLFeed{
+			Title:    title,
+			URL:      feedURL,
+			Category: feedCategory,
+		})
+	}
+}
+
+// opmlCategoryAttr returns the first entry of an OPML category attribute,
+// e.g. "/Tech/News,/Daily" becomes "Tech/News"
+func opmlCategoryAttr(attr string) string {
+	first, _, _ := strings.Cut(attr, ",")
+	return strings.Trim(strings.TrimSpace(first), "/")
+}
+
+// ImportOPMLFeeds adds the given feeds to the database and to cfg, skipping any whose
+// URL is already subscribed. Each feed's ID is generated from its title, with a numeric
+// suffix if another feed already uses it, so distinct feeds with the same title are all
+// imported. It returns the number of feeds imported. The caller is responsible for
+// saving cfg.
+func ImportOPMLFeeds(db *sql.DB, cfg *config.Config, opmlFeeds []OPMLFeed) int {
+	existingIDs := make(map[string]bool)
+	existingURLs := make(map[string]bool)
+	for _, f := range cfg.Feeds {
+		existingIDs[f.ID] = true
+		existingURLs[f.URL] = true
+	}
+	// Feeds only in the database still own their ID; reusing it would overwrite them
+	dbFeeds, err := storage.ListFeeds(db, false)
+	if err != nil {
+		slog.Error("Error listing feeds", "err", err)
+		return 0
+	}
+	for _, f := range dbFeeds {
+		existingIDs[f.ID] = true
+	}
+
+	imported := 0
+	for _, of := range opmlFeeds {
+		if existingURLs[of.URL] {
+			continue
+		}
+		feedID := config.UniqueID(config.Slugify(of.Title), func(id string) bool { return existingIDs[id] })
+
+		feed := &storage.Feed{
+			ID:       feedID,
+			Name:     of.Title,
+			URL:      of.URL,
+			Category: of.Category,
+			Enabled:  true,
+		}
+		if err := storage.UpsertFeed(db, feed); err != nil {
+			slog.Error("Error importing feed", "url", of.URL, "err", err)
+			continue
+		}
+
+		refreshInterval := 10
+		cfg.Feeds = append(cfg.Feeds, config.FeedConfig{
+			ID:                     feedID,
+			Name:                   of.Title,
+			URL:                    of.URL,
+			Category:               of.Category,
+			Enabled:                true,
+			RefreshIntervalMinutes: &refreshInterval,
+		})
+		existingIDs[feedID] = true
+		existingURLs[of.URL] = true
+		imported++
+	}
+
+	return imported
+}
@@ -0,0 +1,112 @@
+package feeds
+
+import (
+	"reflect"
+	"testing"
+
+	"calmnews/internal/config"
+	"calmnews/internal/storage"
+)
+
+func TestParseOPMLNestedCategories(t *testing.T) {
+	data := []byte(`<?xml version="1.0"?>
+<opml version="2.0">
+  <body>
+    <outline text="Tech">
+      <outline text="Lobsters" xmlUrl="https://lobste.rs/rss"/>
+      <outline text="Languages">
+        <outline title="Go Blog" text="ignored" xmlUrl="https://go.dev/blog/feed.atom"/>
+      </outline>
+    </outline>
+    <outline text="Loose" xmlUrl="https://example.com/loose.xml" category="/Daily/News,/Other"/>
+    <outline text="Plain" xmlUrl="https://example.com/plain.xml"/>
+  </body>
+</opml>`)
+
+	got, err := ParseOPML(data)
+	if err != nil {
+		t.Fatalf("ParseOPML: %v", err)
+	}
+	want := []OPMLFeed{
+		{Title: "Lobsters", URL: "https://lobste.rs/rss", Category: "Tech"},
+		{Title: "Go Blog", URL: "https://go.dev/blog/feed.atom", Category: "Languages"},
+		{Title: "Loose", URL: "https://example.com/loose.xml", Category: "Daily/News"},
+		{Title: "Plain", URL: "https://example.com/plain.xml", Category: defaultOPMLCategory},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseOPML:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseOPMLMissingAttributes(t *testing.T) {
+	data := []byte(`<opml><body>
+  <outline xmlUrl="https://news.example.com/rss"/>
+  <outline text="  " xmlUrl="  https://spaces.example.com/feed  "/>
+  <outline text="Empty folder"/>
+  <outline text="No URL folder"><outline text="Child" xmlUrl=""/></outline>
+</body></opml>`)
+
+	got, err := ParseOPML(data)
+	if err != nil {
+		t.Fatalf("ParseOPML: %v", err)
+	}
+	want := []OPMLFeed{
+		{Title: "news.example.com", URL: "https://news.example.com/rss", Category: defaultOPMLCategory},
+		{Title: "spaces.example.com", URL: "https://spaces.example.com/feed", Category: defaultOPMLCategory},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseOPML:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseOPMLInvalid(t *testing.T) {
+	if _, err := ParseOPML([]byte("<opml><body><outline")); err == nil {
+		t.Error("ParseOPML accepted truncated XML")
+	}
+}
+
+func TestImportOPMLFeedsDuplicates(t *testing.T) {
+	db := openTestDB(t)
+	cfg := &config.Config{Feeds: []config.FeedConfig{
+		{ID: "blog", Name: "Blog", URL: "https://one.example.com/feed", Category: "news", Enabled: true},
+	}}
+	// A feed only in the database still owns its ID
+	if err := storage.UpsertFeedSettings(db, &storage.Feed{ID: "news", Name: "News", URL: "https://old.example.com/feed", Category: "news"}); err != nil {
+		t.Fatalf("UpsertFeedSettings: %v", err)
+	}
+
+	imported := ImportOPMLFeeds(db, cfg, []OPMLFeed{
+		{Title: "Blog", URL: "https://one.example.com/feed", Category: "news"}, // already subscribed
+		{Title: "Blog", URL: "https://two.example.com/feed", Category: "news"},
+		{Title: "Blog", URL: "https://three.example.com/feed", Category: "news"},
+		{Title: "Blog", URL: "https://two.example.com/feed", Category: "news"}, // repeated in the file
+		{Title: "News", URL: "https://new.example.com/feed", Category: "news"},
+	})
+	if imported != 3 {
+		t.Errorf("imported %d feeds, want 3", imported)
+	}
+
+	gotURLs := make(map[string]string)
+	for _, f := range cfg.Feeds {
+		gotURLs[f.ID] = f.URL
+	}
+	wantURLs := map[string]string{
+		"blog":   "https://one.example.com/feed",
+		"blog-2": "https://two.example.com/feed",
+		"blog-3": "https://three.example.com/feed",
+		"news-2": "https://new.example.com/feed",
+	}
+	if !reflect.DeepEqual(gotURLs, wantURLs) {
+		t.Errorf("config feeds = %v, want %v", gotURLs, wantURLs)
+	}
+
+	for id, url := range map[string]string{"news": "https://old.example.com/feed", "blog-2": "https://two.example.com/feed", "news-2": "https://new.example.com/feed"} {
+		feed, err := storage.GetFeedByID(db, id)
+		if err != nil {
+			t.Fatalf("GetFeedByID(%s): %v", id, err)
+		}
+		if feed.URL != url {
+			t.Errorf("feed %s URL = %s, want %s", id, feed.URL, url)
+		}
+	}
+}
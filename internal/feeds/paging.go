@@ -0,0 +1,128 @@
+package feeds
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/atom"
+	"github.com/mmcdole/gofeed/rss"
+
+	"calmnews/internal/config"
+	"calmnews/internal/storage"
+)
+
+// atomPagingTranslator is the default Atom translator, additionally recording the
+// feed's next-page link, which gofeed's universal Feed doesn't carry
+type atomPagingTranslator struct {
+	gofeed.DefaultAtomTranslator
+	nextURL string
+}
+
+// Translate implements gofeed.Translator
+func (t *atomPagingTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	if atomFeed, ok := feed.(*atom.Feed); ok {
+		for _, link := range atomFeed.Links {
+			if strings.EqualFold(link.Rel, "next") && strings.TrimSpace(link.Href) != "" {
+				t.nextURL = strings.TrimSpace(link.Href)
+				break
+			}
+		}
+	}
+	return t.DefaultAtomTranslator.Translate(feed)
+}
+
+// rssNextLink returns the href of an <atom:link rel="next"> in an RSS channel, or ""
+func rssNextLink(feed *rss.Feed) string {
+	for _, elements := range feed.Extensions {
+		for _, link := range elements["link"] {
+			if strings.EqualFold(link.Attrs["rel"], "next") && strings.TrimSpace(link.Attrs["href"]) != "" {
+				return strings.TrimSpace(link.Attrs["href"])
+			}
+		}
+	}
+	return ""
+}
+
+// followNextPagesLimit returns how many pages after the first to fetch for a feed
+func followNextPagesLimit(cfg *config.Config, feedID string) int {
+	for _, feedCfg := range cfg.Feeds {
+		if feedCfg.ID == feedID {
+			return feedCfg.FollowNextPages
+		}
+	}
+	return 0
+}
+
+// followNextPages fetches the pages after the first by following rel="next" links, for
+// feeds with follow_next_pages set, and adds their items to parsed. pageURL is where
+// the first page was served from, which relative links are resolved against. It stops
+// at the page limit, at a page without a next link, once a page includes an article
+// that is already stored (nothing older was missed), or at the first error; a page
+// that fails is logged but doesn't fail the fetch.
+func followNextPages(db *sql.DB, cfg *config.Config, feed *storage.Feed, parsed *ParsedFeed, pageURL string) {
+	limit := followNextPagesLimit(cfg, feed.ID)
+	visited := map[string]bool{feed.URL: true, pageURL: true}
+	page := parsed
+	for i := 0; i < limit && page.NextURL != ""; i++ {
+		ids := make([]string, len(page.Articles))
+		for j, article := range page.Articles {
+			ids[j] = article.ID
+		}
+		known, err := storage.AnyArticleExists(db, ids)
+		if err != nil {
+			slog.Error("Error checking for stored articles", "feed_id", feed.ID, "err", err)
+			return
+		}
+		if known {
+			return
+		}
+
+		nextURL, ok := resolveNextURL(pageURL, page.NextURL)
+		if !ok || visited[nextURL] {
+			slog.Warn("Not following feed next-page link", "feed_id", feed.ID, "url", page.NextURL)
+			return
+		}
+		visited[nextURL] = true
+
+		result, err := FetchFeed(nextURL)
+		if err != nil {
+			slog.Warn("Error fetching next feed page", "feed_id", feed.ID, "url", nextURL, "err", err)
+			return
+		}
+		// Article IDs use the feed's own ID URL on every page, like on the first
+		page, err = ParseFeed(result.Data, result.ContentType, nextURL, feed.ArticleIDURL(), feed.ID, feed.Name, feedDateFormat(cfg, feed.ID))
+		if err != nil {
+			slog.Warn("Error parsing next feed page", "feed_id", feed.ID, "url", nextURL, "err", err)
+			return
+		}
+		slog.Debug("Fetched next feed page", "feed_id", feed.ID, "url", nextURL, "count", len(page.Articles))
+
+		parsed.Articles = append(parsed.Articles, page.Articles...)
+		parsed.Skipped = append(parsed.Skipped, page.Skipped...)
+		for id := range page.Untitled {
+			parsed.Untitled[id] = true
+		}
+		pageURL = result.FinalURL
+	}
+}
+
+// resolveNextURL resolves a next-page link against the URL of the page it appeared on.
+// ok is false if it isn't a valid http(s) URL.
+func resolveNextURL(pageURL string, next string) (string, bool) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", false
+	}
+	ref, err := url.Parse(next)
+	if err != nil {
+		return "", false
+	}
+	u := base.ResolveReference(ref)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	return u.String(), true
+}
@@ -1,77 +1,266 @@
 package feeds
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/mmcdole/gofeed"
 	"calmnews/internal/storage"
 )
 
-// ParseFeed parses RSS/Atom feed data and returns normalized articles
-func ParseFeed(data []byte, feedURL string, feedID string, sourceName string) ([]*storage.Article, error) {
-	fp := gofeed.NewParser()
-	feed, err := fp.ParseString(string(data))
+// ParsedFeed is the result of parsing a feed document
+type ParsedFeed struct {
+	Articles []*storage.Article
+	Hints    ScheduleHints
+	// NextURL is the feed's rel="next" link to its next page (RFC 5005) as written in
+	// the feed, possibly relative; empty if it has none
+	NextURL string
+	// Skipped describes the items that couldn't be turned into articles, one entry each
+	Skipped []string
+	// SiteURL is the website the feed belongs to and IconURL its favicon; either may be empty
+	SiteURL string
+	IconURL string
+	// Untitled holds the IDs of articles whose item had no title, so their title was
+	// generated from their text and shouldn't be used to detect duplicates
+	Untitled map[string]bool
+}
+
+// fallbackTitleChars is the length of a title generated for an item without one
+const fallbackTitleChars = 80
+
+// ParseFeed parses RSS/Atom feed data and returns normalized articles along with the
+// feed's schedule hints. contentType is the HTTP Content-Type of the response, used to
+// detect the charset when the XML declaration doesn't specify one. Items that can't be
+// normalized are skipped and listed in Skipped rather than failing the whole feed; an
+// error is returned only if the document itself can't be parsed, or ErrHTMLNotFeed if
+// it is an HTML page rather than a feed. dateFormat, if set, is a Go time layout used for
+// item dates gofeed can't parse itself. Article IDs are derived from idURL (see
+// storage.Feed.ArticleIDURL), while feedURL is where the document was fetched from.
+func ParseFeed(data []byte, contentType string, feedURL string, idURL string, feedID string, sourceName string, dateFormat string) (*ParsedFeed, error) {
+	feed, extras, err := parseFeedData(data, contentType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse feed: %w", err)
+		return nil, err
 	}
 
-	var articles []*storage.Article
-	now := time.Now()
+	parsed := &ParsedFeed{Hints: extras.hints, NextURL: extras.nextURL, Untitled: make(map[string]bool)}
+	parsed.SiteURL, parsed.IconURL = siteInfo(feed, feedURL)
+	// In UTC like the dates gofeed parses, since stored times are compared as text
+	now := time.Now().UTC()
 
-	for _, item := range feed.Items {
-		// Use GUID if available, otherwise use link
-		entryGUID := item.GUID
-		if entryGUID == "" {
-			entryGUID = item.Link
+	for i, item := range feed.Items {
+		article, err := normalizeItem(feed, item, idURL, feedID, sourceName, dateFormat, now)
+		if err != nil {
+			parsed.Skipped = append(parsed.Skipped, fmt.Sprintf("item %d: %v", i+1, err))
+			continue
+		}
+		if strings.TrimSpace(item.Title) == "" {
+			parsed.Untitled[article.ID] = true
 		}
+		parsed.Articles = append(parsed.Articles, article)
+	}
 
-		articleID := storage.GenerateArticleID(feedURL, entryGUID)
+	return parsed, nil
+}
 
-		// Parse published date
-		var publishedAt time.Time
-		if item.PublishedParsed != nil {
-			publishedAt = *item.PublishedParsed
-		} else if item.UpdatedParsed != nil {
-			publishedAt = *item.UpdatedParsed
-		} else {
-			publishedAt = now
+// normalizeItem converts a parsed feed item into an article. It returns an error for
+// items that have nothing to identify or show them by, and recovers from panics on
+// malformed items so one bad item can't take down the fetch.
+func normalizeItem(feed *gofeed.Feed, item *gofeed.Item, idURL string, feedID string, sourceName string, dateFormat string, now time.Time) (article *storage.Article, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			article, err = nil, fmt.Errorf("malformed item: %v", r)
 		}
+	}()
+
+	if item == nil {
+		return nil, errors.New("empty item")
+	}
+
+	// Use GUID if available, otherwise use link
+	entryGUID := strings.TrimSpace(item.GUID)
+	if entryGUID == "" {
+		entryGUID = strings.TrimSpace(item.Link)
+	}
+	// Without either, every such item would get the same ID
+	if entryGUID == "" {
+		return nil, errors.New("no guid or link")
+	}
+	if strings.TrimSpace(item.Title) == "" && strings.TrimSpace(item.Link) == "" {
+		return nil, errors.New("no title or link")
+	}
+
+	articleID := storage.GenerateArticleID(idURL, entryGUID)
 
-		// Extract summary/description
-		summary := ""
-		if item.Description != "" {
-			summary = item.Description
-		} else if item.Content != "" {
-			summary = item.Content
+	// Parse published date
+	publishedAt, ok := itemDate(item, dateFormat)
+	if !ok {
+		publishedAt = now
+	}
+
+	// Summary is the short description as plain text and Content the full body as HTML.
+	// They are kept separate so the reader view only shows real article content.
+	summary := stripHTML(item.Description)
+	content := item.Content
+
+	// Join item categories/tags, skipping blanks
+	var categories []string
+	for _, c := range item.Categories {
+		c = strings.TrimSpace(c)
+		if c != "" {
+			categories = append(categories, c)
 		}
+	}
+
+	// Microblog feeds often have no titles; use the start of the text instead
+	title := strings.TrimSpace(item.Title)
+	if title == "" {
+		title = fallbackTitle(summary, content, item.Link)
+	}
+
+	return &storage.Article{
+		ID:          articleID,
+		FeedID:      feedID,
+		Title:       title,
+		URL:         item.Link,
+		Summary:     summary,
+		Content:     content,
+		PublishedAt: publishedAt,
+		FetchedAt:   now,
+		SourceName:  sourceName,
+		Categories:  strings.Join(categories, ","),
+		ImageURL:    articleImageURL(item),
+		Language:    itemLanguage(feed, item, summary),
+		IsRead:      false,
+		IsSaved:     false,
+	}, nil
+}
 
-		// Extract content
-		content := ""
-		if item.Content != "" {
-			content = item.Content
-		} else if item.Description != "" {
-			content = item.Description
+// itemDate returns the item's published date, or its updated date if it has none. Dates
+// gofeed couldn't parse are parsed with dateFormat, if set. ok is false if neither date
+// could be read.
+func itemDate(item *gofeed.Item, dateFormat string) (date time.Time, ok bool) {
+	if item.PublishedParsed != nil {
+		return *item.PublishedParsed, true
+	}
+	if item.UpdatedParsed != nil {
+		return *item.UpdatedParsed, true
+	}
+	if dateFormat == "" {
+		return time.Time{}, false
+	}
+	for _, raw := range []string{item.Published, item.Updated} {
+		if t, err := time.Parse(dateFormat, strings.TrimSpace(raw)); err == nil {
+			// Stored in UTC like the dates gofeed parses
+			return t.UTC(), true
 		}
+	}
+	return time.Time{}, false
+}
+
+// siteInfo returns the absolute URL of the feed's website (its channel link) and a
+// best-effort favicon URL: /favicon.ico on the website, or on the feed's own host if the
+// feed has no website link. The favicon isn't checked; the templates hide broken icons.
+func siteInfo(feed *gofeed.Feed, feedURL string) (siteURL string, iconURL string) {
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return "", ""
+	}
 
-		article := &storage.Article{
-			ID:          articleID,
-			FeedID:      feedID,
-			Title:       item.Title,
-			URL:         item.Link,
-			Summary:     summary,
-			Content:     content,
-			PublishedAt: publishedAt,
-			FetchedAt:   now,
-			SourceName:  sourceName,
-			Categories:  "",
-			IsRead:      false,
-			IsSaved:     false,
+	if link := strings.TrimSpace(feed.Link); link != "" {
+		if ref, err := url.Parse(link); err == nil {
+			if u := base.ResolveReference(ref); u.Scheme == "http" || u.Scheme == "https" {
+				siteURL = u.String()
+			}
 		}
+	}
+
+	host := base
+	if siteURL != "" {
+		host, _ = url.Parse(siteURL)
+	}
+	if host.Host == "" {
+		return siteURL, ""
+	}
+	return siteURL, (&url.URL{Scheme: host.Scheme, Host: host.Host, Path: "/favicon.ico"}).String()
+}
+
+// fallbackTitle builds a title for an untitled item from the start of its summary,
+// else its content, else its link
+func fallbackTitle(summary string, content string, link string) string {
+	text := summary
+	if text == "" {
+		text = stripHTML(content)
+	}
+	if text == "" {
+		return link
+	}
+	return truncateSummary(text, fallbackTitleChars)
+}
+
+// feedExtras is what parseFeedData reads from a feed beyond gofeed's universal Feed
+type feedExtras struct {
+	hints   ScheduleHints
+	nextURL string
+}
+
+// parseFeedData decodes raw RSS/Atom data into a gofeed.Feed, its schedule hints and
+// its next-page link
+func parseFeedData(data []byte, contentType string) (*gofeed.Feed, feedExtras, error) {
+	// A login or error page would otherwise surface as a vague parse error
+	if err := checkNotHTML(data, contentType); err != nil {
+		return nil, feedExtras{}, err
+	}
+
+	data = toUTF8(data, contentType)
 
-		articles = append(articles, article)
+	rssTranslator := &hintTranslator{}
+	atomTranslator := &atomPagingTranslator{}
+	fp := gofeed.NewParser()
+	fp.RSSTranslator = rssTranslator
+	fp.AtomTranslator = atomTranslator
+	feed, err := fp.ParseString(string(data))
+	if err != nil {
+		return nil, feedExtras{}, fmt.Errorf("failed to parse feed: %w", err)
+	}
+	extras := feedExtras{hints: rssTranslator.hints, nextURL: rssTranslator.nextURL}
+	if atomTranslator.nextURL != "" {
+		extras.nextURL = atomTranslator.nextURL
 	}
+	return feed, extras, nil
+}
 
-	return articles, nil
+// FeedMeta is the feed-level information of an RSS/Atom feed
+type FeedMeta struct {
+	Title       string
+	Description string
+	Categories  []string
 }
 
+// FetchFeedMeta fetches url, checks that it is a parseable RSS/Atom feed and returns its
+// title, description and categories, any of which may be empty. It gives up when ctx is done.
+func FetchFeedMeta(ctx context.Context, url string) (*FeedMeta, error) {
+	result, err := fetchFeedContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, _, err := parseFeedData(result.Data, result.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &FeedMeta{
+		Title:       strings.TrimSpace(feed.Title),
+		Description: stripHTML(feed.Description),
+	}
+	for _, c := range feed.Categories {
+		if c = strings.TrimSpace(c); c != "" {
+			meta.Categories = append(meta.Categories, c)
+		}
+	}
+	return meta, nil
+}
@@ -0,0 +1,75 @@
+package feeds
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFeedUndatedItemUsesUTC(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC+5", 5*3600)
+	t.Cleanup(func() { time.Local = local })
+
+	data := []byte(`<rss version="2.0"><channel><title>T</title>
+<item><title>Undated</title><link>https://example.com/a</link></item>
+</channel></rss>`)
+	parsed, err := ParseFeed(data, "application/rss+xml", "https://example.com/feed", "https://example.com/feed", "f", "F", "")
+	if err != nil {
+		t.Fatalf("ParseFeed: %v", err)
+	}
+	if len(parsed.Articles) != 1 {
+		t.Fatalf("got %d articles, want 1", len(parsed.Articles))
+	}
+	article := parsed.Articles[0]
+	if article.PublishedAt.Location() != time.UTC || article.FetchedAt.Location() != time.UTC {
+		t.Errorf("published_at %v and fetched_at %v, want both in UTC", article.PublishedAt, article.FetchedAt)
+	}
+	if d := time.Since(article.PublishedAt); d < 0 || d > time.Minute {
+		t.Errorf("published_at = %v, want the fetch time", article.PublishedAt)
+	}
+}
+
+func TestParseFeedUntitledItems(t *testing.T) {
+	long := strings.Repeat("word ", 40)
+	data := []byte(`<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/"><channel><title>T</title>
+<item><guid>summary</guid><link>https://social.example.com/1</link><description>&lt;p&gt;Just shipped a &lt;b&gt;new release&lt;/b&gt;!&lt;/p&gt;</description></item>
+<item><guid>content</guid><link>https://social.example.com/2</link><content:encoded><![CDATA[<p>Only <i>content</i> here</p>]]></content:encoded></item>
+<item><guid>link</guid><link>https://social.example.com/3</link></item>
+<item><guid>long</guid><link>https://social.example.com/4</link><description>` + long + `</description></item>
+<item><guid>blank</guid><title>   </title><link>https://social.example.com/5</link><description>Blank title</description></item>
+<item><guid>titled</guid><title>A real title</title><link>https://social.example.com/6</link><description>Body</description></item>
+</channel></rss>`)
+	parsed, err := ParseFeed(data, "application/rss+xml", "https://social.example.com/feed", "https://social.example.com/feed", "f", "F", "")
+	if err != nil {
+		t.Fatalf("ParseFeed: %v", err)
+	}
+	if len(parsed.Articles) != 6 {
+		t.Fatalf("got %d articles, want 6", len(parsed.Articles))
+	}
+
+	want := []struct {
+		title    string
+		untitled bool
+	}{
+		{"Just shipped a new release!", true},
+		{"Only content here", true},
+		{"https://social.example.com/3", true},
+		{"", true}, // checked separately below
+		{"Blank title", true},
+		{"A real title", false},
+	}
+	for i, article := range parsed.Articles {
+		if w := want[i]; w.title != "" && article.Title != w.title {
+			t.Errorf("item %d: title = %q, want %q", i+1, article.Title, w.title)
+		}
+		if got := parsed.Untitled[article.ID]; got != want[i].untitled {
+			t.Errorf("item %d: untitled = %v, want %v", i+1, got, want[i].untitled)
+		}
+	}
+
+	title := parsed.Articles[3].Title
+	if n := len([]rune(title)); n > fallbackTitleChars+1 || !strings.HasSuffix(title, "…") || !strings.HasPrefix(title, "word word") {
+		t.Errorf("long item: title = %q (%d runes), want the start of the text cut at a word", title, n)
+	}
+}
@@ -0,0 +1,71 @@
+package feeds
+
+import (
+	"sync"
+	"time"
+)
+
+// fetchWindow is the period scheduler.max_fetches_per_minute is counted over
+const fetchWindow = time.Minute
+
+// fetchSlots limits how many scheduled fetches start per fetchWindow. Feeds that are
+// due but can't get a slot wait for a later window, most overdue first.
+var fetchSlots = struct {
+	sync.Mutex
+	windowStart time.Time
+	used        int
+	// waiting holds the feeds that are due but haven't got a slot, with when they became due
+	waiting map[string]time.Time
+}{waiting: make(map[string]time.Time)}
+
+// takeFetchSlot reports whether a feed that became due at due may be fetched now,
+// given at most limit scheduled fetches per window. If not, the feed is remembered
+// as waiting so that feeds which have waited longer go first in the next window. A
+// limit of zero or less means unlimited.
+func takeFetchSlot(limit int, feedID string, due time.Time, now time.Time) bool {
+	if limit <= 0 {
+		return true
+	}
+
+	fetchSlots.Lock()
+	defer fetchSlots.Unlock()
+
+	if now.Sub(fetchSlots.windowStart) >= fetchWindow {
+		fetchSlots.windowStart = now
+		fetchSlots.used = 0
+	}
+
+	fetchSlots.waiting[feedID] = due
+	free := limit - fetchSlots.used
+	for id, d := range fetchSlots.waiting {
+		if free <= 0 {
+			return false
+		}
+		// Feeds that became due earlier go first; the ID breaks ties consistently
+		if id != feedID && (d.Before(due) || (d.Equal(due) && id < feedID)) {
+			free--
+		}
+	}
+	if free <= 0 {
+		return false
+	}
+
+	fetchSlots.used++
+	delete(fetchSlots.waiting, feedID)
+	return true
+}
+
+// leaveFetchQueue forgets that a feed is waiting for a fetch slot, because it is no
+// longer due or its fetch loop has stopped
+func leaveFetchQueue(feedID string) {
+	fetchSlots.Lock()
+	defer fetchSlots.Unlock()
+	delete(fetchSlots.waiting, feedID)
+}
+
+// untilNextFetchWindow returns how long until the next window's slots are available
+func untilNextFetchWindow(now time.Time) time.Duration {
+	fetchSlots.Lock()
+	defer fetchSlots.Unlock()
+	return max(fetchSlots.windowStart.Add(fetchWindow).Sub(now), time.Second)
+}
@@ -0,0 +1,66 @@
+package feeds
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"calmnews/internal/config"
+)
+
+// maxRawFeedsPerFeed is how many unparseable responses are kept per feed
+const maxRawFeedsPerFeed = 5
+
+// rawFeedDir is the data dir subdirectory unparseable responses are written to
+const rawFeedDir = "failed-feeds"
+
+// saveRawFeed writes a response that couldn't be parsed to the failed-feeds
+// directory under the data dir, as <name>.<UTC timestamp>.xml, and removes the
+// feed's oldest files beyond maxRawFeedsPerFeed. It returns the path written.
+func saveRawFeed(feedID string, data []byte, now time.Time) (string, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(dataDir, rawFeedDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	name := rawFeedName(feedID)
+	path := filepath.Join(dir, name+"."+now.UTC().Format("20060102T150405.000Z")+".xml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	// Timestamps sort by name, oldest first
+	files, err := filepath.Glob(filepath.Join(dir, name+".*.xml"))
+	if err != nil {
+		return path, err
+	}
+	slices.Sort(files)
+	for len(files) > maxRawFeedsPerFeed {
+		if err := os.Remove(files[0]); err != nil {
+			return path, fmt.Errorf("failed to remove %s: %w", files[0], err)
+		}
+		files = files[1:]
+	}
+	return path, nil
+}
+
+// rawFeedName returns the file name prefix for feedID's raw responses: the ID itself
+// if it is already a slug, otherwise its slug plus "_" and a short hash of the ID, so
+// IDs that slugify alike don't share (and prune) each other's files. Names never
+// contain dots, so the glob in saveRawFeed can't match another feed's files.
+func rawFeedName(feedID string) string {
+	slug := config.Slugify(feedID)
+	if slug == feedID {
+		return slug
+	}
+	hash := sha256.Sum256([]byte(feedID))
+	return slug + "_" + hex.EncodeToString(hash[:4])
+}
@@ -0,0 +1,41 @@
+package feeds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveRawFeedKeepsFeedsApart(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("CALMNEWS_DATA_DIR", dataDir)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// "My Feed" and "my-feed" slugify alike, but each keeps its own files
+	for i := range maxRawFeedsPerFeed + 2 {
+		if _, err := saveRawFeed("my-feed", []byte("slug"), now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("saveRawFeed: %v", err)
+		}
+	}
+	path, err := saveRawFeed("My Feed", []byte("raw"), now)
+	if err != nil {
+		t.Fatalf("saveRawFeed: %v", err)
+	}
+	for i := range maxRawFeedsPerFeed {
+		if _, err := saveRawFeed("my-feed", []byte("slug"), now.Add(time.Hour+time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("saveRawFeed: %v", err)
+		}
+	}
+
+	if data, err := os.ReadFile(path); err != nil || string(data) != "raw" {
+		t.Errorf("My Feed's file %s: %q, %v; want it kept", path, data, err)
+	}
+	files, err := filepath.Glob(filepath.Join(dataDir, rawFeedDir, "my-feed.*.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != maxRawFeedsPerFeed {
+		t.Errorf("my-feed has %d files, want %d: %v", len(files), maxRawFeedsPerFeed, files)
+	}
+}
@@ -0,0 +1,189 @@
+package feeds
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// ContentExtractor pulls the main article body out of a web page and returns it as HTML
+type ContentExtractor interface {
+	Extract(page []byte, contentType string, pageURL string) (string, error)
+}
+
+// ErrNoArticleContent is returned when a page has no recognizable article body
+var ErrNoArticleContent = errors.New("no article content found")
+
+// minExtractedTextLen is the least text an extracted body must have to be worth keeping;
+// anything shorter is likely a teaser, a paywall or a cookie notice
+const minExtractedTextLen = 250
+
+// boilerplateElements are removed with their contents before scoring
+var boilerplateElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "iframe": true, "form": true,
+	"nav": true, "header": true, "footer": true, "aside": true, "button": true,
+	"svg": true, "select": true, "textarea": true, "template": true,
+}
+
+// boilerplateClassRe matches class and id values of blocks that are rarely part of the article
+var boilerplateClassRe = regexp.MustCompile(`(?i)comment|share|sharing|social|related|sidebar|newsletter|promo|subscribe|advert|cookie`)
+
+// ReadabilityExtractor finds the article body in the spirit of Mozilla's Readability: it
+// scores each block by the paragraphs it contains, penalizes link-heavy blocks and keeps
+// the best one
+type ReadabilityExtractor struct{}
+
+// Extract implements ContentExtractor
+func (ReadabilityExtractor) Extract(page []byte, contentType string, pageURL string) (string, error) {
+	reader, err := charset.NewReader(bytes.NewReader(page), contentType)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect page charset: %w", err)
+	}
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	removeBoilerplate(doc)
+	best := bestContentNode(doc)
+	if best == nil || len(strings.TrimSpace(textContent(best))) < minExtractedTextLen {
+		return "", ErrNoArticleContent
+	}
+
+	if base, err := url.Parse(pageURL); err == nil {
+		resolveRelativeURLs(best, base)
+	}
+
+	var buf bytes.Buffer
+	for c := best.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&buf, c); err != nil {
+			return "", fmt.Errorf("failed to render article content: %w", err)
+		}
+	}
+	return buf.String(), nil
+}
+
+// removeBoilerplate deletes navigation, scripts and similar blocks from the tree
+func removeBoilerplate(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && isBoilerplate(c)) {
+			n.RemoveChild(c)
+		} else {
+			removeBoilerplate(c)
+		}
+		c = next
+	}
+}
+
+// isBoilerplate reports whether an element should be removed before scoring
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateElements[n.Data] {
+		return true
+	}
+	// Only container blocks are judged by class; body and article often carry
+	// page-wide classes such as "has-sidebar"
+	if n.Data != "div" && n.Data != "section" && n.Data != "ul" {
+		return false
+	}
+	for _, attr := range n.Attr {
+		if (attr.Key == "class" || attr.Key == "id") && boilerplateClassRe.MatchString(attr.Val) {
+			return true
+		}
+	}
+	return false
+}
+
+// bestContentNode returns the element whose paragraphs make up most of the article, or nil
+func bestContentNode(doc *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.Data == "p" || n.Data == "pre" || n.Data == "blockquote") {
+			text := strings.TrimSpace(textContent(n))
+			if len(text) >= 25 {
+				// Longer paragraphs with more clauses are more likely to be prose
+				score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+				if parent := n.Parent; parent != nil {
+					scores[parent] += score
+					if grandparent := parent.Parent; grandparent != nil {
+						scores[grandparent] += score / 2
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	var best *html.Node
+	bestScore := 0.0
+	for n, score := range scores {
+		if n.Data == "article" || n.Data == "main" {
+			score *= 1.25
+		}
+		score *= 1 - linkDensity(n)
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	return best
+}
+
+// linkDensity returns the share of a node's text that sits inside links
+func linkDensity(n *html.Node) float64 {
+	total := len(textContent(n))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			linked += len(textContent(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(linked) / float64(total)
+}
+
+// textContent returns the concatenated text of a node and its descendants
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+// resolveRelativeURLs makes link and image URLs absolute so they work outside the page
+func resolveRelativeURLs(n *html.Node, base *url.URL) {
+	if n.Type == html.ElementNode {
+		for i, attr := range n.Attr {
+			if (n.Data == "a" && attr.Key == "href") || (n.Data == "img" && attr.Key == "src") {
+				if ref, err := url.Parse(strings.TrimSpace(attr.Val)); err == nil {
+					n.Attr[i].Val = base.ResolveReference(ref).String()
+				}
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		resolveRelativeURLs(c, base)
+	}
+}
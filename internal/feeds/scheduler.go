@@ -2,109 +2,502 @@ package feeds
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"calmnews/internal/config"
+	"calmnews/internal/dedup"
 	"calmnews/internal/storage"
 )
 
-// StartScheduler starts a background goroutine that periodically fetches and updates feeds
-func StartScheduler(db *sql.DB, cfg *config.Config, refreshIntervalMinutes int) {
+const (
+	// maintenanceInterval is how often expired articles are cleaned up and remote
+	// blocklists are checked
+	maintenanceInterval = 10 * time.Minute
+	// superviseInterval is how often the scheduler looks for new or re-enabled feeds
+	superviseInterval = time.Minute
+	// maxStartupJitter spreads out the first fetch of each feed so they don't all fire at once
+	maxStartupJitter = 30 * time.Second
+	// minFetchGap is the shortest time between two scheduled fetches of the same feed
+	minFetchGap = time.Minute
+	// maxDueWait is the longest a feed loop sleeps before re-reading its feed, so mutes,
+	// unmutes and other changes take effect promptly
+	maxDueWait = time.Minute
+)
+
+// StartScheduler starts background goroutines that fetch each enabled feed on its own
+// refresh interval, extract full text for feeds that enable it, periodically clean
+// up expired articles and keep remote blocklists up to date. The current config is
+// read from store on every run, so reloaded settings take effect without a restart.
+// It returns right away; fetching begins after scheduler.startup_delay_seconds, with
+// each feed's first fetch further staggered.
+func StartScheduler(db *sql.DB, store *config.Store) {
+	// Maintenance loop: cleanup runs immediately, then on every tick
 	go func() {
-		ticker := time.NewTicker(time.Duration(refreshIntervalMinutes) * time.Minute)
+		ticker := time.NewTicker(maintenanceInterval)
 		defer ticker.Stop()
 
-		// Do an initial fetch immediately
-		fetchAllFeeds(db, cfg)
+		cleanupExpiredArticles(db, store.Get())
+		catchUpOldArticles(db, store.Get())
+		refreshRemoteBlocklists(store.Get())
+
+		for range ticker.C {
+			cleanupExpiredArticles(db, store.Get())
+			catchUpOldArticles(db, store.Get())
+			refreshRemoteBlocklists(store.Get())
+		}
+	}()
+
+	// Full-text extraction for feeds that opt in
+	go runFullTextWorker(db)
 
-		// Do an initial cleanup
-		cleanupExpiredArticles(db)
+	// Supervisor loop: start a fetch loop for every enabled feed, including ones
+	// added or re-enabled from the settings page after startup
+	go func() {
+		if delay := store.Get().Scheduler.StartupDelay(); delay > 0 {
+			slog.Info("Delaying first feed fetches", "delay", delay)
+			time.Sleep(delay)
+		}
 
+		ticker := time.NewTicker(superviseInterval)
+		defer ticker.Stop()
+
+		startFeedLoops(db, store, store.Get().Scheduler.RefreshOnStart)
 		for range ticker.C {
-			fetchAllFeeds(db, cfg)
-			// Cleanup expired articles after each fetch cycle
-			cleanupExpiredArticles(db)
+			startFeedLoops(db, store, false)
 		}
 	}()
 }
 
-// cleanupExpiredArticles removes articles older than 72 hours (except saved ones)
-func cleanupExpiredArticles(db *sql.DB) {
-	deleted, err := storage.DeleteExpiredArticles(db, 72)
+// feedLoops tracks which feeds have a running fetch loop
+var (
+	feedLoopsMu sync.Mutex
+	feedLoops   = make(map[string]bool)
+)
+
+// startFeedLoops starts a fetch loop for each enabled feed that doesn't have one. With
+// force, each loop fetches its feed once right away even if it isn't due.
+func startFeedLoops(db *sql.DB, store *config.Store, force bool) {
+	feeds, err := storage.ListFeeds(db, true) // Only enabled feeds
 	if err != nil {
-		log.Printf("Error cleaning up expired articles: %v", err)
+		slog.Error("Error listing feeds", "err", err)
+		return
+	}
+
+	feedLoopsMu.Lock()
+	defer feedLoopsMu.Unlock()
+	for _, feed := range feeds {
+		if feedLoops[feed.ID] {
+			continue
+		}
+		feedLoops[feed.ID] = true
+		go runFeedLoop(db, store, feed.ID, force)
+	}
+}
+
+// runFeedLoop fetches a single feed whenever it is due, or first right away with force
+// unless it is muted. It exits when the feed is deleted or disabled; the supervisor
+// starts it again if the feed comes back.
+func runFeedLoop(db *sql.DB, store *config.Store, feedID string, force bool) {
+	defer func() {
+		feedLoopsMu.Lock()
+		delete(feedLoops, feedID)
+		feedLoopsMu.Unlock()
+		leaveFetchQueue(feedID)
+	}()
+
+	time.Sleep(rand.N(maxStartupJitter))
+
+	for {
+		// Reload every time so edits, toggles, config reloads and fetch results are picked up
+		feed, err := storage.GetFeedByID(db, feedID)
+		if err != nil || !feed.Enabled {
+			return
+		}
+
+		cfg := store.Get()
+		now := time.Now()
+		wait := timeUntilDue(cfg, feed, now)
+		if force && !feed.Muted(now) {
+			wait = 0
+		}
+		if wait > 0 {
+			leaveFetchQueue(feedID)
+			time.Sleep(min(wait, maxDueWait))
+			continue
+		}
+
+		// With a fetch rate limit, due feeds may have to wait for a later window
+		if !takeFetchSlot(cfg.Scheduler.MaxFetchesPerMinute, feedID, now.Add(wait), now) {
+			time.Sleep(untilNextFetchWindow(now) + rand.N(time.Second))
+			continue
+		}
+
+		fetchFeedOnce(db, cfg, feed)
+		force = false
+		time.Sleep(minFetchGap)
+	}
+}
+
+// timeUntilDue returns how long until a feed should next be fetched, based on its
+// refresh interval, any mute, the feed's own schedule hints and, for failing feeds, the
+// backoff since the last attempt. A zero or negative duration means the feed is due now.
+func timeUntilDue(cfg *config.Config, feed *storage.Feed, now time.Time) time.Duration {
+	interval := effectiveInterval(cfg, feed)
+
+	var due time.Time // zero: never fetched, due immediately
+	if feed.LastFetchedAt != nil {
+		due = feed.LastFetchedAt.Add(interval)
+	}
+
+	// Failing feeds are retried less and less often until they recover
+	if feed.ConsecutiveFailures > 0 && feed.LastAttemptAt != nil {
+		retry := feed.LastAttemptAt.Add(backoffInterval(interval, feed.ConsecutiveFailures))
+		if retry.After(due) {
+			due = retry
+		}
+	}
+
+	// Muted feeds resume on their own once the mute expires
+	if feed.Muted(now) && feed.MutedUntil.After(due) {
+		due = *feed.MutedUntil
+	}
+
+	if due.IsZero() {
+		return 0
+	}
+	if honorsScheduleHints(cfg, feed.ID) {
+		due = skipHintedHours(feed, due)
+	}
+	return due.Sub(now)
+}
+
+// maxTTLInterval caps how far a feed's <ttl> can stretch its refresh interval
+const maxTTLInterval = 24 * time.Hour
+
+// effectiveInterval returns the feed's refresh interval, lengthened to its published
+// <ttl> when the feed asks to be polled less often and hints aren't ignored
+func effectiveInterval(cfg *config.Config, feed *storage.Feed) time.Duration {
+	interval := refreshInterval(cfg, feed.ID)
+	if feed.TTLMinutes > 0 && honorsScheduleHints(cfg, feed.ID) {
+		ttl := min(time.Duration(feed.TTLMinutes)*time.Minute, maxTTLInterval)
+		interval = max(interval, ttl)
+	}
+	return interval
+}
+
+// honorsScheduleHints reports whether a feed's own polling hints should be followed
+func honorsScheduleHints(cfg *config.Config, feedID string) bool {
+	for _, feedCfg := range cfg.Feeds {
+		if feedCfg.ID == feedID {
+			return !feedCfg.IgnoreScheduleHints
+		}
+	}
+	return true
+}
+
+// feedDateFormat returns the date layout configured for a feed, or "" if none
+func feedDateFormat(cfg *config.Config, feedID string) string {
+	for _, feedCfg := range cfg.Feeds {
+		if feedCfg.ID == feedID {
+			return feedCfg.DateFormat
+		}
+	}
+	return ""
+}
+
+// skipHintedHours moves t forward to the start of the first hour that isn't in the
+// feed's skipHours or skipDays. If the hints rule out every hour of the week they are
+// ignored and t is returned unchanged.
+func skipHintedHours(feed *storage.Feed, t time.Time) time.Time {
+	if len(feed.SkipHours) == 0 && len(feed.SkipDays) == 0 {
+		return t
+	}
+	next := t
+	for range 7 * 24 {
+		utc := next.UTC()
+		if !slices.Contains(feed.SkipHours, utc.Hour()) && !slices.Contains(feed.SkipDays, utc.Weekday()) {
+			return next
+		}
+		next = utc.Truncate(time.Hour).Add(time.Hour)
+	}
+	return t
+}
+
+// cleanupExpiredArticles removes articles older than the configured retention, except saved ones,
+// those from feeds marked never_expire and, with keep_disabled_feeds, those from disabled
+// feeds. Deleted feeds none of whose saved articles are left are removed too. A retention
+// of zero disables cleanup.
+func cleanupExpiredArticles(db *sql.DB, cfg *config.Config) {
+	retentionHours := cfg.Articles.RetentionHoursOrDefault()
+	if retentionHours <= 0 {
+		return
+	}
+	var exempt []string
+	for _, f := range cfg.Feeds {
+		if f.NeverExpire || (!f.Enabled && cfg.Articles.KeepDisabledFeeds) {
+			exempt = append(exempt, f.ID)
+		}
+	}
+	deleted, err := storage.DeleteExpiredArticles(db, retentionHours, exempt)
+	if err != nil {
+		slog.Error("Error cleaning up expired articles", "err", err)
 		return
 	}
 	if deleted > 0 {
-		log.Printf("Cleaned up %d expired articles", deleted)
+		slog.Info("Cleaned up expired articles", "count", deleted)
+	}
+	// Deleted feeds are kept only while some of their saved articles are
+	if err := storage.DeleteUnusedFeeds(db); err != nil {
+		slog.Error("Error removing unused deleted feeds", "err", err)
 	}
 }
 
-func fetchAllFeeds(db *sql.DB, cfg *config.Config) {
-	feeds, err := storage.ListFeeds(db, true) // Only enabled feeds
+// catchUpOldArticles marks stale unread articles as read when a catch-up window is configured
+func catchUpOldArticles(db *sql.DB, cfg *config.Config) {
+	if cfg.Articles.CatchUpDays <= 0 {
+		return
+	}
+	olderThan := time.Duration(cfg.Articles.CatchUpDays) * 24 * time.Hour
+	updated, err := storage.MarkOldUnreadRead(db, olderThan)
 	if err != nil {
-		log.Printf("Error listing feeds: %v", err)
+		slog.Error("Error marking old articles as read", "err", err)
 		return
 	}
+	if updated > 0 {
+		slog.Info("Marked old unread articles as read", "count", updated)
+	}
+}
 
-	now := time.Now()
-	defaultInterval := 10 * time.Minute
+// defaultRefreshInterval is used for feeds without refresh_interval_minutes
+const defaultRefreshInterval = 10 * time.Minute
 
-	for _, feed := range feeds {
-		// Check if enough time has passed since last fetch
-		if feed.LastFetchedAt != nil {
-			// Find refresh interval for this feed
-			interval := defaultInterval
-			for _, feedCfg := range cfg.Feeds {
-				if feedCfg.ID == feed.ID && feedCfg.RefreshIntervalMinutes != nil {
-					interval = time.Duration(*feedCfg.RefreshIntervalMinutes) * time.Minute
-					break
-				}
-			}
+// refreshInterval returns the configured refresh interval for a feed: its own
+// refresh_interval_minutes, else its category's interval, else defaultRefreshInterval
+func refreshInterval(cfg *config.Config, feedID string) time.Duration {
+	for _, feedCfg := range cfg.Feeds {
+		if feedCfg.ID != feedID {
+			continue
+		}
+		if feedCfg.RefreshIntervalMinutes != nil {
+			return time.Duration(*feedCfg.RefreshIntervalMinutes) * time.Minute
+		}
+		if minutes := cfg.Scheduler.CategoryIntervalMinutes[feedCfg.Category]; minutes > 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+		break
+	}
+	return defaultRefreshInterval
+}
 
-			timeSinceLastFetch := now.Sub(*feed.LastFetchedAt)
-			if timeSinceLastFetch < interval {
-				continue // Skip this feed, not enough time has passed
+// maxBackoffInterval caps how long a failing feed waits between attempts
+const maxBackoffInterval = 12 * time.Hour
+
+// backoffInterval doubles the refresh interval for each consecutive failure, up to maxBackoffInterval
+func backoffInterval(interval time.Duration, failures int) time.Duration {
+	backoff := interval
+	for i := 0; i < failures && backoff < maxBackoffInterval; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoffInterval {
+		backoff = maxBackoffInterval
+	}
+	return backoff
+}
+
+// inFlight tracks feeds currently being fetched so that manual and scheduled
+// fetches of the same feed never overlap
+var (
+	inFlightMu sync.Mutex
+	inFlight   = make(map[string]bool)
+)
+
+// fetchFeedOnce fetches and stores a feed unless a fetch of it is already running.
+// It returns false if the feed was skipped.
+func fetchFeedOnce(db *sql.DB, cfg *config.Config, feed *storage.Feed) bool {
+	inFlightMu.Lock()
+	if inFlight[feed.ID] {
+		inFlightMu.Unlock()
+		slog.Debug("Skipping feed, fetch already in progress", "feed_id", feed.ID)
+		return false
+	}
+	inFlight[feed.ID] = true
+	inFlightMu.Unlock()
+
+	defer func() {
+		inFlightMu.Lock()
+		delete(inFlight, feed.ID)
+		inFlightMu.Unlock()
+	}()
+
+	fetchErr := fetchAndStoreFeed(db, cfg, feed)
+	if err := storage.RecordFeedFetchResult(db, feed.ID, fetchErr); err != nil {
+		slog.Error("Error recording fetch result", "feed_id", feed.ID, "err", err)
+	}
+	if fetchErr != nil {
+		failures := feed.ConsecutiveFailures + 1
+		slog.Warn("Error fetching feed", "feed_id", feed.ID, "url", feed.URL, "err", fetchErr,
+			"failures", failures, "next_attempt_in", backoffInterval(effectiveInterval(cfg, feed), failures))
+	}
+	return true
+}
+
+// RefreshNow fetches all enabled, unmuted feeds, or only feedID if it is non-empty, in the
+// background, ignoring refresh intervals. It returns the number of feeds queued
+// without waiting for the fetches to finish. The scheduler keeps its own timing.
+func RefreshNow(db *sql.DB, cfg *config.Config, feedID string) (int, error) {
+	var feeds []*storage.Feed
+	if feedID != "" {
+		feed, err := storage.GetFeedByID(db, feedID)
+		if err != nil {
+			return 0, err
+		}
+		feeds = []*storage.Feed{feed}
+	} else {
+		enabled, err := storage.ListFeeds(db, true) // Only enabled feeds
+		if err != nil {
+			return 0, err
+		}
+		// An explicit refresh of one feed overrides its mute; refreshing everything doesn't
+		now := time.Now()
+		for _, feed := range enabled {
+			if !feed.Muted(now) {
+				feeds = append(feeds, feed)
 			}
 		}
+	}
 
-		// Fetch the feed
-		if err := fetchAndStoreFeed(db, cfg, feed); err != nil {
-			log.Printf("Error fetching feed %s (%s): %v", feed.Name, feed.URL, err)
-			continue
+	go func() {
+		for _, feed := range feeds {
+			fetchFeedOnce(db, cfg, feed)
 		}
+	}()
 
-		log.Printf("Successfully fetched feed: %s", feed.Name)
-	}
+	return len(feeds), nil
 }
 
 func fetchAndStoreFeed(db *sql.DB, cfg *config.Config, feed *storage.Feed) error {
 	// Fetch feed data
-	data, err := FetchFeed(feed.URL)
+	start := time.Now()
+	result, err := FetchFeed(feed.URL)
 	if err != nil {
+		attrs := []any{"feed_id", feed.ID, "duration", time.Since(start).Round(time.Millisecond)}
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			attrs = append(attrs, "status", statusErr.StatusCode)
+		}
+		slog.Debug("Feed fetch failed", attrs...)
 		return fmt.Errorf("failed to fetch: %w", err)
 	}
+	slog.Debug("Fetched feed", "feed_id", feed.ID, "status", result.StatusCode, "bytes", len(result.Data),
+		"duration", result.Duration.Round(time.Millisecond))
+
+	// Remember a permanent move so the subscription can be updated from the settings page.
+	// Article IDs keep using the feed's original URL so existing articles aren't duplicated.
+	if result.MovedTo != feed.MovedTo {
+		if result.MovedTo != "" {
+			slog.Info("Feed has moved permanently", "feed_id", feed.ID, "url", feed.URL, "moved_to", result.MovedTo)
+		}
+		if err := storage.SetFeedMovedTo(db, feed.ID, result.MovedTo); err != nil {
+			slog.Error("Error recording new feed URL", "feed_id", feed.ID, "err", err)
+		}
+	}
 
 	// Parse feed
-	articles, err := ParseFeed(data, feed.URL, feed.ID, feed.Name)
+	parsed, err := ParseFeed(result.Data, result.ContentType, feed.URL, feed.ArticleIDURL(), feed.ID, feed.Name, feedDateFormat(cfg, feed.ID))
 	if err != nil {
+		if cfg.Scheduler.SaveFailedFeeds {
+			if path, saveErr := saveRawFeed(feed.ID, result.Data, time.Now()); saveErr != nil {
+				slog.Error("Error saving unparseable feed", "feed_id", feed.ID, "err", saveErr)
+			} else {
+				slog.Info("Saved unparseable feed response", "feed_id", feed.ID, "path", path)
+			}
+		}
 		return fmt.Errorf("failed to parse: %w", err)
 	}
+	followNextPages(db, cfg, feed, parsed, result.FinalURL)
+	articles, hints := parsed.Articles, parsed.Hints
+	if len(parsed.Skipped) > 0 {
+		slog.Warn("Skipped malformed feed items", "feed_id", feed.ID, "count", len(parsed.Skipped), "reasons", strings.Join(parsed.Skipped, "; "))
+	}
 
-	// Filter out duplicate articles by title
+	if parsed.SiteURL != feed.SiteURL || parsed.IconURL != feed.IconURL {
+		if err := storage.SetFeedSiteInfo(db, feed.ID, parsed.SiteURL, parsed.IconURL); err != nil {
+			slog.Error("Error storing feed site info", "feed_id", feed.ID, "err", err)
+		}
+	}
+
+	// Keep the feed's polling hints for the scheduler; they rarely change
+	if hints.TTLMinutes != feed.TTLMinutes || !slices.Equal(hints.SkipHours, feed.SkipHours) || !slices.Equal(hints.SkipDays, feed.SkipDays) {
+		if hints.TTLMinutes > 0 {
+			slog.Info("Feed asks to be fetched less often", "feed_id", feed.ID, "ttl_minutes", hints.TTLMinutes)
+		}
+		if err := storage.SetFeedScheduleHints(db, feed.ID, hints.TTLMinutes, hints.SkipHours, hints.SkipDays); err != nil {
+			slog.Error("Error storing feed schedule hints", "feed_id", feed.ID, "err", err)
+		}
+	}
+
+	// Articles with the same ID are merged by UpsertArticles; optionally also
+	// filter out articles whose title already exists
+	trackingParams := cfg.Articles.TrackingParamsOrDefault()
+	languages := cfg.Articles.LanguageCodes()
+	dedupScope := cfg.Articles.TitleDedupScope()
+	contentDedupSince := time.Now().Add(-cfg.Articles.ContentDedupWindow())
+	seenHashes := make(map[string]bool)
+	skippedLanguage := 0
 	var uniqueArticles []*storage.Article
 	for _, article := range articles {
-		exists, err := storage.ArticleExistsByTitle(db, article.Title)
-		if err != nil {
-			log.Printf("Error checking for duplicate article %s: %v", article.Title, err)
-			// Continue with other articles, but don't skip this one
-		} else if exists {
-			log.Printf("Skipping duplicate article: %s", article.Title)
+		article.Summary = truncateSummary(article.Summary, cfg.Articles.SummaryMaxChars)
+		// Only the stored link is cleaned; the article ID still comes from the original
+		// link so existing articles aren't duplicated
+		article.URL = stripTrackingParams(article.URL, trackingParams)
+
+		// Articles of unknown language are always kept
+		if len(languages) > 0 && article.Language != "" && !slices.Contains(languages, article.Language) {
+			skippedLanguage++
 			continue
 		}
+
+		// Always store the hash, so enabling content dedup takes effect right away
+		article.ContentHash = dedup.ContentHash(article.Title, article.Summary)
+		if cfg.Articles.DedupByContent {
+			// Reposts can also repeat within one fetch, before either copy is stored
+			exists := seenHashes[article.ContentHash]
+			if !exists {
+				var err error
+				exists, err = storage.ArticleExistsByContentHash(db, article.ContentHash, article.ID, contentDedupSince)
+				if err != nil {
+					slog.Error("Error checking for duplicate content", "feed_id", feed.ID, "article_id", article.ID, "err", err)
+				}
+			}
+			if exists {
+				slog.Debug("Skipping article with duplicate content", "feed_id", feed.ID, "article_id", article.ID, "title", article.Title)
+				continue
+			}
+			seenHashes[article.ContentHash] = true
+		}
+
+		// Generated titles say nothing about whether two items are the same story
+		if dedupScope != config.DedupScopeNone && !parsed.Untitled[article.ID] {
+			scopeFeedID := ""
+			if dedupScope == config.DedupScopePerFeed {
+				scopeFeedID = article.FeedID
+			}
+			exists, err := storage.ArticleExistsByTitle(db, article.Title, article.ID, scopeFeedID)
+			if err != nil {
+				slog.Error("Error checking for duplicate article", "feed_id", feed.ID, "title", article.Title, "err", err)
+				// Continue with other articles, but don't skip this one
+			} else if exists {
+				slog.Debug("Skipping duplicate article", "feed_id", feed.ID, "title", article.Title)
+				continue
+			}
+		}
 		// Auto-trash articles whose URL matches the URL blocklist
 		lowerURL := strings.ToLower(article.URL)
 		for _, blocked := range cfg.URLBlocklist {
@@ -116,14 +509,31 @@ func fetchAndStoreFeed(db *sql.DB, cfg *config.Config, feed *storage.Feed) error
 		uniqueArticles = append(uniqueArticles, article)
 	}
 
-	// Store unique articles
-	for _, article := range uniqueArticles {
-		if err := storage.UpsertArticle(db, article); err != nil {
-			log.Printf("Error upserting article %s: %v", article.ID, err)
-			// Continue with other articles
+	if skippedLanguage > 0 {
+		slog.Info("Skipped articles in other languages", "feed_id", feed.ID, "count", skippedLanguage, "languages", languages)
+	}
+
+	// Store unique articles in one transaction
+	if err := storage.UpsertArticles(db, uniqueArticles); err != nil {
+		return fmt.Errorf("failed to store articles: %w", err)
+	}
+
+	// Keep high-volume feeds from crowding out the rest of the database
+	if max := cfg.Articles.MaxArticlesPerFeed; max > 0 {
+		trimmed, err := storage.TrimFeedArticles(db, feed.ID, max)
+		if err != nil {
+			return fmt.Errorf("failed to trim articles: %w", err)
+		}
+		if trimmed > 0 {
+			slog.Info("Trimmed old articles", "feed_id", feed.ID, "count", trimmed)
 		}
 	}
 
+	// Fetch full article bodies in the background for feeds that only ship teasers
+	if fullTextEnabled(cfg, feed.ID) {
+		queueFullText(db, feed)
+	}
+
 	// Update last_fetched_at
 	now := time.Now()
 	if err := storage.UpdateFeedLastFetched(db, feed.ID, now); err != nil {
@@ -0,0 +1,407 @@
+package feeds
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"calmnews/internal/config"
+	"calmnews/internal/storage"
+)
+
+// openTestDB opens a fresh, migrated database in a temporary directory
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "news.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestStartupHonorsLastFetch(t *testing.T) {
+	db := openTestDB(t)
+	cfg := &config.Config{Feeds: []config.FeedConfig{
+		{ID: "recent", Name: "Recent", URL: "https://example.com/recent.xml", Category: "news", Enabled: true, RefreshIntervalMinutes: intPtr(30)},
+		{ID: "new", Name: "New", URL: "https://example.com/new.xml", Category: "news", Enabled: true, RefreshIntervalMinutes: intPtr(30)},
+	}}
+	sync := func() {
+		for _, f := range cfg.Feeds {
+			feed := &storage.Feed{ID: f.ID, Name: f.Name, URL: f.URL, Category: f.Category, Enabled: f.Enabled}
+			if err := storage.UpsertFeedSettings(db, feed); err != nil {
+				t.Fatalf("UpsertFeedSettings: %v", err)
+			}
+		}
+	}
+
+	// First run: "recent" is fetched five minutes before shutdown
+	sync()
+	now := time.Now()
+	if err := storage.UpdateFeedLastFetched(db, "recent", now.Add(-5*time.Minute)); err != nil {
+		t.Fatalf("UpdateFeedLastFetched: %v", err)
+	}
+	if err := storage.RecordFeedFetchResult(db, "recent", nil); err != nil {
+		t.Fatalf("RecordFeedFetchResult: %v", err)
+	}
+
+	// Restart: the config is synced into the database again
+	sync()
+
+	recent, err := storage.GetFeedByID(db, "recent")
+	if err != nil {
+		t.Fatalf("GetFeedByID: %v", err)
+	}
+	if recent.LastFetchedAt == nil {
+		t.Fatal("startup sync cleared last_fetched_at")
+	}
+	wait := timeUntilDue(cfg, recent, now)
+	if wait < 24*time.Minute || wait > 26*time.Minute {
+		t.Errorf("recently fetched feed: timeUntilDue = %v, want about 25m", wait)
+	}
+
+	never, err := storage.GetFeedByID(db, "new")
+	if err != nil {
+		t.Fatalf("GetFeedByID: %v", err)
+	}
+	if wait := timeUntilDue(cfg, never, now); wait > 0 {
+		t.Errorf("never fetched feed: timeUntilDue = %v, want due now", wait)
+	}
+}
+
+func TestRefreshIntervalPrecedence(t *testing.T) {
+	cfg := &config.Config{
+		Feeds: []config.FeedConfig{
+			{ID: "own", Category: "news", RefreshIntervalMinutes: intPtr(5)},
+			{ID: "category", Category: "news"},
+			{ID: "other-category", Category: "tech"},
+			{ID: "no-category-interval", Category: "sports"},
+		},
+		Scheduler: config.SchedulerConfig{CategoryIntervalMinutes: map[string]int{"news": 30, "tech": 15}},
+	}
+
+	tests := []struct {
+		feedID string
+		want   time.Duration
+	}{
+		{"own", 5 * time.Minute},                         // feed's own interval beats its category's
+		{"category", 30 * time.Minute},                   // category interval
+		{"other-category", 15 * time.Minute},             // each category has its own
+		{"no-category-interval", defaultRefreshInterval}, // category without an interval
+		{"unknown", defaultRefreshInterval},              // feed not in the config
+	}
+	for _, tt := range tests {
+		if got := refreshInterval(cfg, tt.feedID); got != tt.want {
+			t.Errorf("refreshInterval(%s) = %v, want %v", tt.feedID, got, tt.want)
+		}
+	}
+
+	cfg.Scheduler.CategoryIntervalMinutes = nil
+	if got := refreshInterval(cfg, "category"); got != defaultRefreshInterval {
+		t.Errorf("without category intervals: refreshInterval = %v, want %v", got, defaultRefreshInterval)
+	}
+}
+
+// serveFeeds serves the given documents by path and returns the server's base URL.
+// Documents can be changed while the server runs.
+func serveFeeds(t *testing.T, docs map[string]string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc, ok := docs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, doc)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// rssDoc builds an RSS document with one item per "guid|title" pair
+func rssDoc(items ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?><rss version="2.0"><channel><title>Feed</title>`)
+	for _, item := range items {
+		guid, title, _ := strings.Cut(item, "|")
+		fmt.Fprintf(&b, `<item><guid>%s</guid><title>%s</title><link>https://example.com/%s</link></item>`, guid, title, guid)
+	}
+	b.WriteString(`</channel></rss>`)
+	return b.String()
+}
+
+// syncTestFeeds stores the config's feeds in the database
+func syncTestFeeds(t *testing.T, db *sql.DB, cfg *config.Config) {
+	t.Helper()
+	for _, f := range cfg.Feeds {
+		feed := &storage.Feed{ID: f.ID, Name: f.Name, URL: f.URL, Category: f.Category, Enabled: f.Enabled}
+		if err := storage.UpsertFeedSettings(db, feed); err != nil {
+			t.Fatalf("UpsertFeedSettings: %v", err)
+		}
+	}
+}
+
+// fetchTestFeed runs a full fetch of feedID, failing the test on error
+func fetchTestFeed(t *testing.T, db *sql.DB, cfg *config.Config, feedID string) {
+	t.Helper()
+	feed, err := storage.GetFeedByID(db, feedID)
+	if err != nil {
+		t.Fatalf("GetFeedByID: %v", err)
+	}
+	if err := fetchAndStoreFeed(db, cfg, feed); err != nil {
+		t.Fatalf("fetchAndStoreFeed(%s): %v", feedID, err)
+	}
+}
+
+// feedTitles returns the titles of a feed's stored articles, sorted
+func feedTitles(t *testing.T, db *sql.DB, feedID string) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT title FROM articles WHERE feed_id = ? ORDER BY title;`, feedID)
+	if err != nil {
+		t.Fatalf("query titles: %v", err)
+	}
+	defer rows.Close()
+	var titles []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			t.Fatalf("scan title: %v", err)
+		}
+		titles = append(titles, title)
+	}
+	return titles
+}
+
+func TestSameTitleFromDifferentFeedsIsStored(t *testing.T) {
+	base := serveFeeds(t, map[string]string{
+		"/a.xml": rssDoc("a1|Weekly roundup", "a2|Only in A"),
+		"/b.xml": rssDoc("b1|Weekly roundup"),
+	})
+
+	for _, scope := range []string{"", config.DedupScopeNone, config.DedupScopePerFeed, config.DedupScopeGlobal} {
+		t.Run("scope="+scope, func(t *testing.T) {
+			db := openTestDB(t)
+			cfg := &config.Config{
+				Feeds: []config.FeedConfig{
+					{ID: "a", Name: "A", URL: base + "/a.xml", Category: "news", Enabled: true},
+					{ID: "b", Name: "B", URL: base + "/b.xml", Category: "news", Enabled: true},
+				},
+				Articles: config.ArticlesConfig{DedupScope: scope},
+			}
+			syncTestFeeds(t, db, cfg)
+			fetchTestFeed(t, db, cfg, "a")
+			fetchTestFeed(t, db, cfg, "b")
+
+			want := 1 // the same headline from another feed is a different article
+			if scope == config.DedupScopeGlobal {
+				want = 0 // unless title dedup across all feeds is turned on
+			}
+			if got := len(feedTitles(t, db, "b")); got != want {
+				t.Errorf("feed b stored %d articles, want %d", got, want)
+			}
+			if got := len(feedTitles(t, db, "a")); got != 2 {
+				t.Errorf("feed a stored %d articles, want 2", got)
+			}
+		})
+	}
+}
+
+func TestRetitledArticleIsUpdated(t *testing.T) {
+	docs := map[string]string{"/a.xml": rssDoc("a1|Draft headline")}
+	base := serveFeeds(t, docs)
+	db := openTestDB(t)
+	cfg := &config.Config{Feeds: []config.FeedConfig{{ID: "a", Name: "A", URL: base + "/a.xml", Category: "news", Enabled: true}}}
+	syncTestFeeds(t, db, cfg)
+
+	fetchTestFeed(t, db, cfg, "a")
+	docs["/a.xml"] = rssDoc("a1|Final headline")
+	fetchTestFeed(t, db, cfg, "a")
+
+	if got := feedTitles(t, db, "a"); len(got) != 1 || got[0] != "Final headline" {
+		t.Errorf("titles = %q, want the one article with its new title", got)
+	}
+}
+
+func TestEditedArticleIsUpdatedWithTitleDedup(t *testing.T) {
+	doc := func(summary string) string {
+		return `<rss version="2.0"><channel><title>A</title><item><guid>a1</guid><title>Headline</title>` +
+			`<link>https://example.com/a1</link><description>` + summary + `</description></item></channel></rss>`
+	}
+
+	for _, scope := range []string{config.DedupScopePerFeed, config.DedupScopeGlobal} {
+		t.Run("scope="+scope, func(t *testing.T) {
+			docs := map[string]string{"/a.xml": doc("First draft")}
+			base := serveFeeds(t, docs)
+			db := openTestDB(t)
+			cfg := &config.Config{
+				Feeds:    []config.FeedConfig{{ID: "a", Name: "A", URL: base + "/a.xml", Category: "news", Enabled: true}},
+				Articles: config.ArticlesConfig{DedupScope: scope},
+			}
+			syncTestFeeds(t, db, cfg)
+
+			fetchTestFeed(t, db, cfg, "a")
+			docs["/a.xml"] = doc("Corrected text")
+			fetchTestFeed(t, db, cfg, "a")
+
+			// The stored copy of the same item doesn't count as a duplicate of its edit
+			var summary string
+			if err := db.QueryRow(`SELECT summary FROM articles WHERE feed_id = ?;`, "a").Scan(&summary); err != nil {
+				t.Fatalf("query summary: %v", err)
+			}
+			if summary != "Corrected text" {
+				t.Errorf("summary = %q, want the edited text", summary)
+			}
+		})
+	}
+}
+
+func TestEditedFeedURLKeepsArticleIDs(t *testing.T) {
+	base := serveFeeds(t, map[string]string{
+		"/old.xml": rssDoc("a1|First", "a2|Second"),
+		"/new.xml": rssDoc("a1|First", "a2|Second", "a3|Third"),
+	})
+	db := openTestDB(t)
+	cfg := &config.Config{Feeds: []config.FeedConfig{{ID: "a", Name: "A", URL: base + "/old.xml", Category: "news", Enabled: true}}}
+	syncTestFeeds(t, db, cfg)
+	fetchTestFeed(t, db, cfg, "a")
+
+	// The feed's URL is edited, e.g. after it moved
+	cfg.Feeds[0].URL = base + "/new.xml"
+	syncTestFeeds(t, db, cfg)
+	fetchTestFeed(t, db, cfg, "a")
+
+	if got := feedTitles(t, db, "a"); len(got) != 3 {
+		t.Errorf("titles = %q, want each article once", got)
+	}
+	oldID := storage.GenerateArticleID(base+"/old.xml", "a3")
+	if stored := storedArticles(t, db, oldID); !stored[oldID] {
+		t.Error("new article's ID isn't derived from the URL the feed was added with")
+	}
+}
+
+// addOldArticle stores an article of feedID fetched the given time ago
+func addOldArticle(t *testing.T, db *sql.DB, feedID string, id string, age time.Duration) {
+	t.Helper()
+	fetched := time.Now().Add(-age).UTC()
+	article := &storage.Article{ID: id, FeedID: feedID, Title: id, URL: "https://example.com/" + id, PublishedAt: fetched, FetchedAt: fetched}
+	if err := storage.UpsertArticle(db, article); err != nil {
+		t.Fatalf("UpsertArticle: %v", err)
+	}
+}
+
+// storedArticles reports which of ids are still stored
+func storedArticles(t *testing.T, db *sql.DB, ids ...string) map[string]bool {
+	t.Helper()
+	stored := make(map[string]bool)
+	for _, id := range ids {
+		exists, err := storage.AnyArticleExists(db, []string{id})
+		if err != nil {
+			t.Fatalf("AnyArticleExists: %v", err)
+		}
+		stored[id] = exists
+	}
+	return stored
+}
+
+func TestCleanupKeepsNeverExpireFeeds(t *testing.T) {
+	db := openTestDB(t)
+	cfg := &config.Config{Feeds: []config.FeedConfig{
+		{ID: "reference", Name: "Reference", URL: "https://example.com/reference.xml", Category: "docs", Enabled: true, NeverExpire: true},
+		{ID: "news", Name: "News", URL: "https://example.com/news.xml", Category: "news", Enabled: true},
+	}}
+	syncTestFeeds(t, db, cfg)
+	// Well past the default 72 hour retention window
+	addOldArticle(t, db, "reference", "reference-old", 30*24*time.Hour)
+	addOldArticle(t, db, "news", "news-old", 100*time.Hour)
+	addOldArticle(t, db, "news", "news-new", time.Hour)
+
+	cleanupExpiredArticles(db, cfg)
+
+	got := storedArticles(t, db, "reference-old", "news-old", "news-new")
+	if !got["reference-old"] || got["news-old"] || !got["news-new"] {
+		t.Errorf("stored after cleanup: %v, want reference-old and news-new only", got)
+	}
+}
+
+func TestUntitledItemsSkipTitleDedup(t *testing.T) {
+	// Two posts whose text starts the same get the same fallback title
+	text := strings.Repeat("Thread about the conference keynote ", 5)
+	base := serveFeeds(t, map[string]string{
+		"/a.xml": `<rss version="2.0"><channel><title>A</title>
+<item><guid>1</guid><link>https://social.example.com/1</link><description>` + text + `part one</description></item>
+<item><guid>2</guid><link>https://social.example.com/2</link><description>` + text + `part two</description></item>
+</channel></rss>`,
+	})
+	db := openTestDB(t)
+	cfg := &config.Config{
+		Feeds:    []config.FeedConfig{{ID: "a", Name: "A", URL: base + "/a.xml", Category: "social", Enabled: true}},
+		Articles: config.ArticlesConfig{DedupScope: config.DedupScopeGlobal},
+	}
+	syncTestFeeds(t, db, cfg)
+	fetchTestFeed(t, db, cfg, "a")
+
+	titles := feedTitles(t, db, "a")
+	if len(titles) != 2 {
+		t.Fatalf("stored %d articles, want both untitled posts", len(titles))
+	}
+	if titles[0] != titles[1] || titles[0] == "" {
+		t.Errorf("titles = %q, want the same non-empty fallback title", titles)
+	}
+}
+
+func TestCleanupKeepsDisabledFeeds(t *testing.T) {
+	db := openTestDB(t)
+	cfg := &config.Config{
+		Feeds: []config.FeedConfig{
+			{ID: "paused", Name: "Paused", URL: "https://example.com/paused.xml", Category: "news", Enabled: true},
+			{ID: "active", Name: "Active", URL: "https://example.com/active.xml", Category: "news", Enabled: true},
+		},
+		Articles: config.ArticlesConfig{KeepDisabledFeeds: true},
+	}
+	syncTestFeeds(t, db, cfg)
+	addOldArticle(t, db, "paused", "paused-old", 100*time.Hour)
+	addOldArticle(t, db, "active", "active-old", 100*time.Hour)
+
+	// Disable the feed, then let cleanup run past the retention window
+	cfg.Feeds[0].Enabled = false
+	syncTestFeeds(t, db, cfg)
+	cleanupExpiredArticles(db, cfg)
+
+	got := storedArticles(t, db, "paused-old", "active-old")
+	if !got["paused-old"] || got["active-old"] {
+		t.Errorf("stored after cleanup: %v, want only the disabled feed's article", got)
+	}
+
+	// Once re-enabled, its articles expire like any other feed's
+	cfg.Feeds[0].Enabled = true
+	syncTestFeeds(t, db, cfg)
+	cleanupExpiredArticles(db, cfg)
+	if storedArticles(t, db, "paused-old")["paused-old"] {
+		t.Error("re-enabled feed's expired article survived cleanup")
+	}
+}
+
+func TestCleanupDisabledFeedsByDefault(t *testing.T) {
+	db := openTestDB(t)
+	cfg := &config.Config{Feeds: []config.FeedConfig{
+		{ID: "paused", Name: "Paused", URL: "https://example.com/paused.xml", Category: "news", Enabled: false},
+	}}
+	syncTestFeeds(t, db, cfg)
+	addOldArticle(t, db, "paused", "paused-old", 100*time.Hour)
+	addOldArticle(t, db, "paused", "paused-new", time.Hour)
+
+	cleanupExpiredArticles(db, cfg)
+
+	got := storedArticles(t, db, "paused-old", "paused-new")
+	if got["paused-old"] || !got["paused-new"] {
+		t.Errorf("stored after cleanup: %v, want the disabled feed's expired article removed without keep_disabled_feeds", got)
+	}
+}
@@ -0,0 +1,45 @@
+package feeds
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+)
+
+// ErrHTMLNotFeed is returned when a feed URL serves an HTML page, such as a login
+// page or an error page, instead of RSS/Atom
+var ErrHTMLNotFeed = errors.New("feed returned HTML, not a feed")
+
+// sniffLen is how much of the body is inspected to recognize HTML
+const sniffLen = 512
+
+// checkNotHTML returns ErrHTMLNotFeed if data looks like an HTML page rather than a feed.
+// The body decides when it clearly starts like HTML or XML; otherwise an HTML
+// Content-Type is trusted, since some servers send feeds as text/html.
+func checkNotHTML(data []byte, contentType string) error {
+	head := data
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")) // UTF-8 BOM
+	head = bytes.ToLower(bytes.TrimSpace(head))
+
+	for _, prefix := range []string{"<?xml", "<rss", "<feed", "<rdf:rdf"} {
+		if bytes.HasPrefix(head, []byte(prefix)) {
+			return nil
+		}
+	}
+	for _, prefix := range []string{"<!doctype html", "<html", "<head", "<body"} {
+		if bytes.HasPrefix(head, []byte(prefix)) {
+			return ErrHTMLNotFeed
+		}
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == "text/html" {
+		// JSON Feed documents start with an object; anything else served as HTML is a page
+		if !bytes.HasPrefix(head, []byte("{")) {
+			return ErrHTMLNotFeed
+		}
+	}
+	return nil
+}
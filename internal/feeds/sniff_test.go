@@ -0,0 +1,84 @@
+package feeds
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"calmnews/internal/config"
+	"calmnews/internal/storage"
+)
+
+const loginPage = `<!DOCTYPE html>
+<html><head><title>Sign in</title></head>
+<body><form action="/login"><input name="user"></form></body></html>`
+
+func TestCheckNotHTML(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		wantHTML    bool
+	}{
+		{"login page", loginPage, "text/html; charset=utf-8", true},
+		{"html without content type", loginPage, "", true},
+		{"html served as xml", "<html><body>Not found</body></html>", "application/xml", true},
+		{"leading whitespace and BOM", "\xef\xbb\xbf\n  <HTML><body>Error</body></HTML>", "", true},
+		{"bare body", "<body>Oops</body>", "", true},
+		{"html content type, unknown body", "Service unavailable", "text/html", true},
+		{"rss", `<?xml version="1.0"?><rss version="2.0"></rss>`, "application/rss+xml", false},
+		{"rss served as html", `<rss version="2.0"><channel></channel></rss>`, "text/html", false},
+		{"atom", `<feed xmlns="http://www.w3.org/2005/Atom"></feed>`, "", false},
+		{"rdf", `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"></rdf:RDF>`, "", false},
+		{"json feed served as html", `{"version": "https://jsonfeed.org/version/1.1"}`, "text/html", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkNotHTML([]byte(tt.body), tt.contentType)
+			if got := errors.Is(err, ErrHTMLNotFeed); got != tt.wantHTML {
+				t.Errorf("checkNotHTML = %v, want HTML detected: %v", err, tt.wantHTML)
+			}
+		})
+	}
+}
+
+func TestParseFeedHTMLBody(t *testing.T) {
+	_, err := ParseFeed([]byte(loginPage), "text/html", "https://example.com/feed", "https://example.com/feed", "f", "F", "")
+	if !errors.Is(err, ErrHTMLNotFeed) {
+		t.Errorf("ParseFeed = %v, want ErrHTMLNotFeed", err)
+	}
+}
+
+func TestFetchHTMLRecordsFeedError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(loginPage))
+	}))
+	t.Cleanup(srv.Close)
+
+	db := openTestDB(t)
+	cfg := &config.Config{Feeds: []config.FeedConfig{{ID: "f", Name: "F", URL: srv.URL + "/feed.xml", Category: "news", Enabled: true}}}
+	syncTestFeeds(t, db, cfg)
+	feed, err := storage.GetFeedByID(db, "f")
+	if err != nil {
+		t.Fatalf("GetFeedByID: %v", err)
+	}
+
+	if err := fetchAndStoreFeed(db, cfg, feed); !errors.Is(err, ErrHTMLNotFeed) {
+		t.Fatalf("fetchAndStoreFeed = %v, want ErrHTMLNotFeed", err)
+	}
+
+	fetchFeedOnce(db, cfg, feed)
+	feed, err = storage.GetFeedByID(db, "f")
+	if err != nil {
+		t.Fatalf("GetFeedByID: %v", err)
+	}
+	if !strings.Contains(feed.LastError, ErrHTMLNotFeed.Error()) {
+		t.Errorf("last_error = %q, want it to say the feed returned HTML", feed.LastError)
+	}
+	if feed.ConsecutiveFailures != 1 {
+		t.Errorf("consecutive_failures = %d, want 1", feed.ConsecutiveFailures)
+	}
+}
@@ -0,0 +1,83 @@
+package feeds
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// stripHTML converts an HTML fragment to plain text: tags are removed, entities
+// are decoded, script/style contents are dropped and whitespace is collapsed.
+// Malformed markup is handled on a best-effort basis by the HTML tokenizer.
+func stripHTML(s string) string {
+	if !strings.ContainsAny(s, "<&") {
+		return collapseWhitespace(s)
+	}
+
+	z := html.NewTokenizer(strings.NewReader(s))
+	var b strings.Builder
+	skipDepth := 0
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			// io.EOF or a tokenizer error; either way we're done
+			return collapseWhitespace(b.String())
+		case html.TextToken:
+			if skipDepth == 0 {
+				b.Write(z.Text())
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if tag == "script" || tag == "style" {
+				if tt == html.StartTagToken {
+					skipDepth++
+				} else if tt == html.EndTagToken && skipDepth > 0 {
+					skipDepth--
+				}
+			}
+			// Keep words apart across block boundaries like <p> and <br>
+			if blockTags[tag] {
+				b.WriteByte(' ')
+			}
+		}
+	}
+}
+
+// blockTags are elements that imply a word break in plain text
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "figcaption": true, "figure": true,
+	"footer": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "li": true, "ol": true, "p": true, "pre": true,
+	"section": true, "table": true, "td": true, "th": true, "tr": true, "ul": true,
+}
+
+// collapseWhitespace replaces runs of whitespace with a single space and trims the result
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// truncateSummary shortens s to at most maxChars characters, cutting at the last
+// word boundary and appending an ellipsis. A maxChars of 0 or less disables truncation.
+func truncateSummary(s string, maxChars int) string {
+	if maxChars <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= maxChars {
+		return s
+	}
+
+	cut := string(runes[:maxChars])
+	// Back up to the last space so we don't split a word, unless there is none
+	if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRightFunc(cut, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
@@ -0,0 +1,55 @@
+package feeds
+
+import "testing"
+
+func TestStripHTML(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain text", "  Just   text\n here ", "Just text here"},
+		{"nested tags", `<div><p>Hello <b>bold <i>and italic</i></b> world</p></div>`, "Hello bold and italic world"},
+		{"block tags separate words", `<p>One</p><p>Two</p>line<br>break<li>item</li>`, "One Two line break item"},
+		{"inline tags join words", `un<em>believ</em>able`, "unbelievable"},
+		{"entities", `Tom &amp; Jerry&#8217;s &lt;show&gt; &quot;live&quot;&nbsp;now`, "Tom & Jerry’s <show> \"live\" now"},
+		{"bare entity", "R&amp;D", "R&D"},
+		{"script and style dropped", `<style>p { color: red }</style>Text<script>track("x")</script> here`, "Text here"},
+		{"tracking pixel", `Story<img src="https://t.example.com/p.gif" width="1" height="1">`, "Story"},
+		{"attributes", `<a href="https://example.com" style="color:red" onclick="x()">link</a>`, "link"},
+		{"unclosed tags", `<p>Open <b>bold <i>italic`, "Open bold italic"},
+		{"stray close tags", `text</b></div> more</p>`, "text more"},
+		{"unterminated tag", `before <a href="x"`, "before"},
+		{"lone angle bracket", `1 < 2 and 3 > 2`, "1 < 2 and 3 > 2"},
+		{"unclosed script", `visible<script>hidden`, "visible"},
+		{"comment", `a<!-- hidden -->b`, "ab"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripHTML(tt.in); got != tt.want {
+				t.Errorf("stripHTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedStripsSummaryKeepsContent(t *testing.T) {
+	data := []byte(`<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/"><channel><title>T</title>
+<item><title>A</title><link>https://example.com/a</link>
+<description><![CDATA[<p>Caf&eacute; <b>news</b> &amp; more</p>]]></description>
+<content:encoded><![CDATA[<p>Full <b>story</b></p>]]></content:encoded>
+</item></channel></rss>`)
+	parsed, err := ParseFeed(data, "application/rss+xml", "https://example.com/feed", "https://example.com/feed", "f", "F", "")
+	if err != nil {
+		t.Fatalf("ParseFeed: %v", err)
+	}
+	if len(parsed.Articles) != 1 {
+		t.Fatalf("got %d articles, want 1", len(parsed.Articles))
+	}
+	article := parsed.Articles[0]
+	if article.Summary != "Café news & more" {
+		t.Errorf("summary = %q, want plain text", article.Summary)
+	}
+	if article.Content != "<p>Full <b>story</b></p>" {
+		t.Errorf("content = %q, want the original HTML", article.Content)
+	}
+}
@@ -1,32 +1,156 @@
 package filter
 
 import (
+	"log/slog"
+	"net/url"
+	"regexp"
 	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
 
 	"calmnews/internal/storage"
 )
 
+const (
+	// regexPrefix marks a blocklist entry as a regular expression, e.g. "re:^sponsored:"
+	regexPrefix = "re:"
+	// wordPrefix marks a blocklist entry that only matches whole words, e.g. "word:AI"
+	wordPrefix = "word:"
+)
+
+// regexCache holds compiled blocklist regexes keyed by pattern.
+// Invalid patterns are stored as nil so they are only reported once.
+var regexCache sync.Map
+
+// compileRegex returns the compiled case-insensitive regex for pattern, or nil if it is invalid
+func compileRegex(pattern string) *regexp.Regexp {
+	if cached, ok := regexCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		slog.Warn("Skipping invalid blocklist regex", "pattern", pattern, "err", err)
+		re = nil
+	}
+	regexCache.Store(pattern, re)
+	return re
+}
+
+// isWordRune reports whether r is part of a word for whole-word matching
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || r == '_'
+}
+
+// containsWord reports whether phrase occurs in text with no word characters directly
+// before or after it. Boundaries are only required on sides where the phrase itself
+// starts or ends with a word character, so "word:c++" still matches "c++ tips".
+func containsWord(text string, phrase string) bool {
+	first, _ := utf8.DecodeRuneInString(phrase)
+	last, _ := utf8.DecodeLastRuneInString(phrase)
+
+	for offset := 0; offset <= len(text); {
+		i := strings.Index(text[offset:], phrase)
+		if i < 0 {
+			return false
+		}
+		start := offset + i
+		end := start + len(phrase)
+
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		startOK := start == 0 || !isWordRune(first) || !isWordRune(before)
+		endOK := end == len(text) || !isWordRune(last) || !isWordRune(after)
+		if startOK && endOK {
+			return true
+		}
+
+		// Continue searching after the first rune of this match
+		_, size := utf8.DecodeRuneInString(text[start:])
+		offset = start + size
+	}
+	return false
+}
+
+// matchPhrase reports whether a blocklist entry matches the lowercase text blob.
+// Entries prefixed with "re:" are regular expressions, entries prefixed with "word:"
+// match whole words only, and anything else is a case-insensitive substring match.
+func matchPhrase(textBlob string, phrase string) bool {
+	phrase = strings.TrimSpace(phrase)
+
+	if strings.HasPrefix(phrase, wordPrefix) {
+		word := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(phrase, wordPrefix)))
+		return word != "" && containsWord(textBlob, word)
+	}
+
+	if strings.HasPrefix(phrase, regexPrefix) {
+		pattern := strings.TrimSpace(strings.TrimPrefix(phrase, regexPrefix))
+		if pattern == "" {
+			return false
+		}
+		re := compileRegex(pattern)
+		return re != nil && re.MatchString(textBlob)
+	}
+
+	lowerPhrase := strings.ToLower(phrase)
+	if lowerPhrase == "" {
+		return false
+	}
+	return strings.Contains(textBlob, lowerPhrase)
+}
+
 // ShouldFilter returns true if the article should be filtered out based on the blocklist
 func ShouldFilter(article *storage.Article, blocklist []string) bool {
 	if len(blocklist) == 0 {
 		return false
 	}
+	return firstMatch(articleText(article, false), blocklist) != ""
+}
 
-	// Build a lowercase text blob from title and summary
-	textBlob := strings.ToLower(article.Title + " " + article.Summary)
+// articleText builds the lowercase text blob blocklists are matched against: the title
+// and summary, plus the content as plain text when includeContent is set
+func articleText(article *storage.Article, includeContent bool) string {
+	text := article.Title + " " + article.Summary
+	if includeContent && article.Content != "" {
+		text += " " + plainText(article.Content)
+	}
+	return strings.ToLower(text)
+}
 
-	// Check each phrase in the blocklist
+// firstMatch returns the first blocklist phrase that matches the text blob, or ""
+func firstMatch(textBlob string, blocklist []string) string {
 	for _, phrase := range blocklist {
-		lowerPhrase := strings.ToLower(strings.TrimSpace(phrase))
-		if lowerPhrase == "" {
-			continue
-		}
-		if strings.Contains(textBlob, lowerPhrase) {
-			return true
+		if matchPhrase(textBlob, phrase) {
+			return phrase
 		}
 	}
+	return ""
+}
 
-	return false
+// plainText returns the text of an HTML fragment with tags removed, entities decoded
+// and whitespace collapsed, so tag names and attributes like class="trump-card" can't
+// match a phrase but a phrase spanning inline tags like "by <b>crypto</b>" still does
+func plainText(fragment string) string {
+	if !strings.ContainsAny(fragment, "<&") {
+		return strings.Join(strings.Fields(fragment), " ")
+	}
+
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	var b strings.Builder
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(b.String()), " ")
+		case html.TextToken:
+			b.Write(z.Text())
+		default:
+			// Keep words in adjacent elements apart
+			b.WriteByte(' ')
+		}
+	}
 }
 
 // FilterArticles filters a list of articles based on the blocklist
@@ -45,3 +169,133 @@ func FilterArticles(articles []*storage.Article, blocklist []string) ([]*storage
 	return filtered, filteredCount
 }
 
+// Rules holds the lists used to decide whether an article is hidden.
+//
+// Precedence: an article matching any Allowlist entry is never filtered, even if it
+// also matches a blocklist. Otherwise it is filtered if it matches the global
+// Blocklist, the blocklist of its own feed in FeedBlocklists, or if its link
+// points to a domain in DomainBlocklist.
+type Rules struct {
+	Blocklist      []string
+	FeedBlocklists map[string][]string // keyed by feed ID
+	// Allowlist entries are matched against the title, summary and source name,
+	// so they can exempt both phrases and trusted sources
+	Allowlist []string
+	// DomainBlocklist filters articles whose URL host is one of these domains or a subdomain of one
+	DomainBlocklist []string
+	// MatchContent also matches the blocklists against the article content, not just the
+	// title and summary. Content can be large, so this is off unless configured.
+	MatchContent bool
+}
+
+// ShouldFilter returns true if the article should be filtered out under these rules
+func (r Rules) ShouldFilter(article *storage.Article) bool {
+	return r.Match(article) != ""
+}
+
+// Match returns the blocklist phrase or blocked domain that hides the article, or ""
+// if the article is shown. When several entries match, the first one checked wins:
+// global blocklist, then the feed's blocklist, then the domain blocklist.
+func (r Rules) Match(article *storage.Article) string {
+	if len(r.Allowlist) > 0 {
+		allowBlob := strings.ToLower(article.Title + " " + article.Summary + " " + article.SourceName)
+		for _, phrase := range r.Allowlist {
+			if matchPhrase(allowBlob, phrase) {
+				return ""
+			}
+		}
+	}
+
+	feedBlocklist := r.FeedBlocklists[article.FeedID]
+	if len(r.Blocklist) > 0 || len(feedBlocklist) > 0 {
+		textBlob := articleText(article, r.MatchContent)
+		if phrase := firstMatch(textBlob, r.Blocklist); phrase != "" {
+			return phrase
+		}
+		if phrase := firstMatch(textBlob, feedBlocklist); phrase != "" {
+			return phrase
+		}
+	}
+	return matchingDomain(article.URL, r.DomainBlocklist)
+}
+
+// NormalizeDomain reduces a domain or URL such as "https://www.Example.com/path" to
+// a bare lowercase host name ("example.com"). It returns "" if no host can be found.
+func NormalizeDomain(s string) string {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" {
+		return ""
+	}
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimSuffix(u.Hostname(), ".")
+	return strings.TrimPrefix(host, "www.")
+}
+
+// MatchesDomain reports whether articleURL's host is one of domains or a subdomain
+// of one, so "m.example.com" matches "example.com"
+func MatchesDomain(articleURL string, domains []string) bool {
+	return matchingDomain(articleURL, domains) != ""
+}
+
+// matchingDomain returns the entry in domains that articleURL's host falls under, or ""
+func matchingDomain(articleURL string, domains []string) string {
+	if len(domains) == 0 {
+		return ""
+	}
+	host := NormalizeDomain(articleURL)
+	if host == "" {
+		return ""
+	}
+
+	for _, entry := range domains {
+		d := NormalizeDomain(entry)
+		if d == "" {
+			continue
+		}
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return entry
+		}
+	}
+	return ""
+}
+
+// FilterArticlesWithRules filters a list of articles using the given rules
+func FilterArticlesWithRules(articles []*storage.Article, rules Rules) ([]*storage.Article, int) {
+	filtered, filteredCount, _ := FilterArticlesWithStats(articles, rules)
+	return filtered, filteredCount
+}
+
+// FilterArticlesWithStats filters articles like FilterArticlesWithRules and also returns
+// how many articles each blocklist phrase or blocked domain hid, keyed by the entry as
+// configured. Each hidden article is counted once, under the entry Match reports.
+func FilterArticlesWithStats(articles []*storage.Article, rules Rules) ([]*storage.Article, int, map[string]int) {
+	var filtered []*storage.Article
+	filteredCount := 0
+	hits := make(map[string]int)
+
+	for _, article := range articles {
+		if entry := rules.Match(article); entry != "" {
+			filteredCount++
+			hits[entry]++
+			continue
+		}
+		filtered = append(filtered, article)
+	}
+
+	return filtered, filteredCount, hits
+}
+
+// FilterArticlesForFeeds filters articles using the global blocklist merged with the
+// blocklist of each article's feed. feedBlocklists is keyed by feed ID.
+func FilterArticlesForFeeds(articles []*storage.Article, blocklist []string, feedBlocklists map[string][]string) ([]*storage.Article, int) {
+	return FilterArticlesWithRules(articles, Rules{
+		Blocklist:      blocklist,
+		FeedBlocklists: feedBlocklists,
+	})
+}
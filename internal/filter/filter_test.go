@@ -0,0 +1,162 @@
+package filter
+
+import (
+	"testing"
+
+	"calmnews/internal/storage"
+)
+
+func TestWordBoundaryMatching(t *testing.T) {
+	tests := []struct {
+		name   string
+		phrase string
+		title  string
+		want   bool
+	}{
+		{"standalone word", "word:AI", "New AI model released", true},
+		{"inside a longer word", "word:AI", "How to maintain a bike", false},
+		{"case-insensitive", "word:ai", "The rise of Ai", true},
+		{"start of text", "word:AI", "AI everywhere", true},
+		{"end of text", "word:AI", "Everyone talks about AI", true},
+		{"followed by punctuation", "word:AI", "Is it AI? Maybe.", true},
+		{"inside punctuation", "word:AI", `The "AI" hype`, true},
+		{"hyphenated", "word:AI", "AI-generated images", true},
+		{"possessive", "word:Ukraine", "Ukraine's harvest", true},
+		{"prefix of a longer word", "word:Ukraine", "Ukrainean dialects", false},
+		{"suffix of a longer word", "word:war", "Software update", false},
+		{"underscore joins words", "word:AI", "AI_model weights", false},
+		{"digits join words", "word:AI", "AI2 released", false},
+		{"later standalone match", "word:AI", "maintain the AI", true},
+		{"accented letter is a word rune", "word:cafe", "Cafés reopen", false},
+		{"accented phrase", "word:café", "Le café ouvre", true},
+		{"accented phrase inside a word", "word:café", "cafétéria news", false},
+		{"non-Latin script", "word:война", "Новости: война продолжается", true},
+		{"non-Latin script inside a word", "word:война", "Войнами", false},
+		{"combining mark joins words", "word:cafe", "cafe\u0301 news", false},
+		{"multi-word phrase", "word:climate change", "Climate change talks", true},
+		{"multi-word phrase inside words", "word:climate change", "Climate changes", false},
+		{"phrase ending in punctuation", "word:c++", "c++ tips", true},
+		{"phrase ending in punctuation followed by a word rune", "word:c++", "c++x", true},
+		{"phrase starting with punctuation", "word:#ad", "Deals #ad", true},
+		{"empty word", "word:", "anything", false},
+		{"whitespace around prefix", "  word: AI ", "AI news", true},
+		// Without the prefix, entries keep matching as substrings
+		{"substring default", "AI", "How to maintain a bike", true},
+		{"substring default across words", "war", "Software update", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article := &storage.Article{Title: tt.title}
+			if got := ShouldFilter(article, []string{tt.phrase}); got != tt.want {
+				t.Errorf("ShouldFilter(%q, %q) = %v, want %v", tt.title, tt.phrase, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowlistOverridesBlocklist(t *testing.T) {
+	rules := Rules{
+		Blocklist:       []string{"crypto"},
+		FeedBlocklists:  map[string][]string{"markets": {"bitcoin"}},
+		DomainBlocklist: []string{"spam.example.com"},
+		Allowlist:       []string{"security audit", "Trusted Wire", "word:ETF"},
+	}
+	tests := []struct {
+		name    string
+		article storage.Article
+		want    string // the entry that hides the article, "" if shown
+	}{
+		{"blocked only", storage.Article{Title: "Crypto prices fall", SourceName: "Daily"}, "crypto"},
+		{"allowed phrase in title", storage.Article{Title: "Crypto exchange security audit published", SourceName: "Daily"}, ""},
+		{"allowed phrase in summary", storage.Article{Title: "Crypto news", Summary: "Results of the Security Audit", SourceName: "Daily"}, ""},
+		{"allowed source", storage.Article{Title: "Crypto prices fall", SourceName: "Trusted Wire"}, ""},
+		{"allowed whole word", storage.Article{Title: "Crypto ETF approved", SourceName: "Daily"}, ""},
+		{"allowlist respects word boundaries", storage.Article{Title: "Crypto fETFish", SourceName: "Daily"}, "crypto"},
+		{"allowlist beats feed blocklist", storage.Article{FeedID: "markets", Title: "Bitcoin ETF launches", SourceName: "Daily"}, ""},
+		{"feed blocklist without allowlist match", storage.Article{FeedID: "markets", Title: "Bitcoin rallies", SourceName: "Daily"}, "bitcoin"},
+		{"allowlist beats blocked domain", storage.Article{Title: "Security audit results", URL: "https://spam.example.com/a", SourceName: "Daily"}, ""},
+		{"blocked domain", storage.Article{Title: "Weather", URL: "https://spam.example.com/a", SourceName: "Daily"}, "spam.example.com"},
+		{"neither list", storage.Article{Title: "Weather", SourceName: "Daily"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules.Match(&tt.article); got != tt.want {
+				t.Errorf("Match = %q, want %q", got, tt.want)
+			}
+			if got := rules.ShouldFilter(&tt.article); got != (tt.want != "") {
+				t.Errorf("ShouldFilter = %v, want %v", got, tt.want != "")
+			}
+		})
+	}
+
+	// Allowed articles are not counted against the blocklist
+	articles := []*storage.Article{
+		{ID: "1", Title: "Crypto prices fall"},
+		{ID: "2", Title: "Crypto security audit"},
+	}
+	shown, hidden, hits := FilterArticlesWithStats(articles, rules)
+	if len(shown) != 1 || shown[0].ID != "2" || hidden != 1 || hits["crypto"] != 1 {
+		t.Errorf("FilterArticlesWithStats: shown %d, hidden %d, hits %v; want only the allowed article shown", len(shown), hidden, hits)
+	}
+}
+
+func TestMatchContent(t *testing.T) {
+	article := &storage.Article{
+		Title:   "Quarterly results",
+		Summary: "The company reported its earnings.",
+		Content: `<p>Revenue grew, driven by <b>crypto</b> trading.</p><div class="sponsored-box">Ad</div>`,
+	}
+
+	tests := []struct {
+		name         string
+		phrase       string
+		matchContent bool
+		want         bool
+	}{
+		{"phrase only in content, option off", "crypto", false, false},
+		{"phrase only in content, option on", "crypto", true, true},
+		{"phrase spanning tags", "by crypto trading", true, true},
+		{"word match in content", "word:revenue", true, true},
+		{"regex match in content", `re:\bgrew\b`, true, true},
+		{"tag name is not matched", "div", true, false},
+		{"attribute is not matched", "sponsored", true, false},
+		{"phrase in title still matches", "quarterly", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := Rules{Blocklist: []string{tt.phrase}, MatchContent: tt.matchContent}
+			if got := rules.ShouldFilter(article); got != tt.want {
+				t.Errorf("ShouldFilter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Feed blocklists are matched against the content too
+	article.FeedID = "markets"
+	rules := Rules{FeedBlocklists: map[string][]string{"markets": {"crypto"}}, MatchContent: true}
+	if got := rules.Match(article); got != "crypto" {
+		t.Errorf("feed blocklist Match = %q, want \"crypto\"", got)
+	}
+
+	// The package-level ShouldFilter keeps matching only the title and summary
+	if ShouldFilter(article, []string{"crypto"}) {
+		t.Error("ShouldFilter matched the content")
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"no  markup\n", "no markup"},
+		{`<p class="trump-card">Hello</p>`, "Hello"},
+		{"Fish &amp; chips", "Fish & chips"},
+		{"<li>one</li><li>two</li>", "one two"},
+		{"by <b>crypto</b>\n trading", "by crypto trading"},
+	}
+	for _, tt := range tests {
+		if got := plainText(tt.in); got != tt.want {
+			t.Errorf("plainText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package filter
+
+import (
+	"bufio"
+	"bytes"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RemoteList is the last state of a remote blocklist subscription
+type RemoteList struct {
+	URL string
+	// Phrases are from the last successful fetch, kept when later fetches fail
+	Phrases []string
+	// FetchedAt is when the list was last fetched successfully; zero if it never was
+	FetchedAt time.Time
+	// Err is the error of the last fetch if it failed, or nil
+	Err error
+}
+
+// remoteLists caches the remote blocklists keyed by URL
+var remoteLists = struct {
+	sync.RWMutex
+	byURL map[string]*RemoteList
+	// merged caches RemotePhrases(mergedURLs). It is built on first use after a list
+	// is refreshed, so requests don't merge the lists every time.
+	merged     []string
+	mergedURLs []string
+	mergedOK   bool
+}{byURL: make(map[string]*RemoteList)}
+
+// ParseBlocklist reads a blocklist with one phrase per line. Blank lines and lines
+// starting with "#" are skipped, and surrounding whitespace is trimmed. It fails if
+// a line is too long to read.
+func ParseBlocklist(data []byte) ([]string, error) {
+	var phrases []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		phrases = append(phrases, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return phrases, nil
+}
+
+// SetRemoteBlocklist records a successful fetch of the remote blocklist at url
+func SetRemoteBlocklist(url string, phrases []string, fetchedAt time.Time) {
+	remoteLists.Lock()
+	defer remoteLists.Unlock()
+	remoteLists.byURL[url] = &RemoteList{URL: url, Phrases: phrases, FetchedAt: fetchedAt}
+	remoteLists.mergedOK = false
+}
+
+// SetRemoteBlocklistError records a failed fetch of the remote blocklist at url. The
+// phrases from the last successful fetch stay in use.
+func SetRemoteBlocklistError(url string, err error) {
+	remoteLists.Lock()
+	defer remoteLists.Unlock()
+	list := remoteLists.byURL[url]
+	if list == nil {
+		list = &RemoteList{URL: url}
+		remoteLists.byURL[url] = list
+	}
+	list.Err = err
+}
+
+// RemoteBlocklists returns the state of the remote blocklists at urls, in that order.
+// Lists that haven't been fetched yet have no phrases.
+func RemoteBlocklists(urls []string) []RemoteList {
+	remoteLists.RLock()
+	defer remoteLists.RUnlock()
+	lists := make([]RemoteList, 0, len(urls))
+	for _, url := range urls {
+		if list := remoteLists.byURL[url]; list != nil {
+			lists = append(lists, *list)
+		} else {
+			lists = append(lists, RemoteList{URL: url})
+		}
+	}
+	return lists
+}
+
+// RemotePhrases returns the phrases of the remote blocklists at urls, without
+// duplicates. Lists no longer in urls are ignored even if they are still cached. The
+// result is cached until a list is refreshed or urls change; callers must not modify it.
+func RemotePhrases(urls []string) []string {
+	remoteLists.RLock()
+	if remoteLists.mergedOK && slices.Equal(remoteLists.mergedURLs, urls) {
+		defer remoteLists.RUnlock()
+		return remoteLists.merged
+	}
+	remoteLists.RUnlock()
+
+	remoteLists.Lock()
+	defer remoteLists.Unlock()
+	var phrases []string
+	seen := make(map[string]bool)
+	for _, url := range urls {
+		list := remoteLists.byURL[url]
+		if list == nil {
+			continue
+		}
+		for _, phrase := range list.Phrases {
+			if !seen[phrase] {
+				seen[phrase] = true
+				phrases = append(phrases, phrase)
+			}
+		}
+	}
+	remoteLists.merged = phrases
+	remoteLists.mergedURLs = slices.Clone(urls)
+	remoteLists.mergedOK = true
+	return phrases
+}
@@ -0,0 +1,60 @@
+package filter
+
+import (
+	"bufio"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBlocklist(t *testing.T) {
+	got, err := ParseBlocklist([]byte("# shared list\n\n  crypto  \r\nnft\n   # indented comment\nre:^ad\\b\n"))
+	if err != nil {
+		t.Fatalf("ParseBlocklist: %v", err)
+	}
+	want := []string{"crypto", "nft", `re:^ad\b`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseBlocklist = %q, want %q", got, want)
+	}
+
+	// A line longer than the scanner's buffer fails the whole list rather than
+	// silently dropping the rest of it
+	long := "crypto\n" + strings.Repeat("x", bufio.MaxScanTokenSize+1) + "\nnft\n"
+	if got, err := ParseBlocklist([]byte(long)); err == nil {
+		t.Errorf("ParseBlocklist with an overlong line = %q, want an error", got)
+	}
+}
+
+func TestRemotePhrases(t *testing.T) {
+	a, b := "https://lists.example.com/a.txt", "https://lists.example.com/b.txt"
+	now := time.Now()
+	SetRemoteBlocklist(a, []string{"crypto", "nft", "crypto"}, now)
+	SetRemoteBlocklist(b, []string{"nft", "sponsored"}, now)
+
+	if got, want := RemotePhrases([]string{a, b}), []string{"crypto", "nft", "sponsored"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("both lists: %q, want %q", got, want)
+	}
+	// A list dropped from the config stops applying even though it is still cached
+	if got, want := RemotePhrases([]string{b}), []string{"nft", "sponsored"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("list b only: %q, want %q", got, want)
+	}
+
+	// A refresh replaces the cached merge
+	RemotePhrases([]string{a, b})
+	SetRemoteBlocklist(b, []string{"giveaway"}, now)
+	if got, want := RemotePhrases([]string{a, b}), []string{"crypto", "nft", "giveaway"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after refresh: %q, want %q", got, want)
+	}
+
+	// A failed fetch keeps the last good phrases
+	SetRemoteBlocklistError(a, errors.New("timeout"))
+	if got, want := RemotePhrases([]string{a, b}), []string{"crypto", "nft", "giveaway"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after failed fetch: %q, want %q", got, want)
+	}
+
+	if got := RemotePhrases([]string{"https://lists.example.com/unfetched.txt"}); len(got) != 0 {
+		t.Errorf("unfetched list: %q, want none", got)
+	}
+}
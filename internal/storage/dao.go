@@ -4,35 +4,95 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// ErrArticleNotFound is returned when an article lookup matches no rows
+var ErrArticleNotFound = errors.New("article not found")
+
 // Feed represents a feed in the database
 type Feed struct {
-	ID            string
-	Name          string
-	URL           string
-	Category      string
-	Enabled       bool
-	LastFetchedAt *time.Time
+	ID                  string
+	Name                string
+	URL                 string
+	Category            string
+	Enabled             bool
+	LastFetchedAt       *time.Time
+	LastAttemptAt       *time.Time
+	LastSuccessAt       *time.Time
+	LastError           string
+	ConsecutiveFailures int
+	// MovedTo is the URL the feed permanently redirected to on its last fetch, if any
+	MovedTo string
+	// MutedUntil pauses fetching the feed until this time without disabling it; nil if not muted
+	MutedUntil *time.Time
+	// TTLMinutes, SkipHours and SkipDays are the polling hints the feed published on its
+	// last successful fetch. Hours are 0-23 and both are in UTC.
+	TTLMinutes int
+	SkipHours  []int
+	SkipDays   []time.Weekday
+	// SiteURL is the feed's website as given by the feed itself, and IconURL that site's
+	// favicon; both are empty until the feed has been fetched
+	SiteURL string
+	IconURL string
+	// IDURL is the URL the feed was added with. Article IDs are derived from it rather
+	// than from URL, so editing the feed's URL doesn't duplicate its articles.
+	IDURL string
+}
+
+// ArticleIDURL returns the URL the feed's article IDs are derived from
+func (f *Feed) ArticleIDURL() string {
+	if f.IDURL != "" {
+		return f.IDURL
+	}
+	return f.URL
+}
+
+// Muted reports whether the feed is muted at the given time
+func (f *Feed) Muted(now time.Time) bool {
+	return f.MutedUntil != nil && f.MutedUntil.After(now)
 }
 
 // Article represents an article in the database
 type Article struct {
-	ID          string
-	FeedID      string
-	Title       string
-	URL         string
-	Summary     string
-	Content     string
-	PublishedAt time.Time
-	FetchedAt   time.Time
-	SourceName  string
-	Categories   string
-	IsRead       bool
-	IsSaved      bool
-	IsTrashed    bool
+	ID          string    `json:"id"`
+	FeedID      string    `json:"feed_id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Summary     string    `json:"summary"`
+	Content     string    `json:"content"`
+	PublishedAt time.Time `json:"published_at"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	SourceName  string    `json:"source_name"`
+	Categories  string    `json:"categories"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	IsRead      bool      `json:"is_read"`
+	IsSaved     bool      `json:"is_saved"`
+	IsTrashed   bool      `json:"is_trashed"`
+	// ReadAt is when the article was last marked read by the user. It is nil for unread
+	// articles and for articles marked read before it was tracked or by catch-up.
+	ReadAt *time.Time `json:"read_at,omitempty"`
+	// SavedAt is when the article was last saved; nil for unsaved articles
+	SavedAt *time.Time `json:"saved_at,omitempty"`
+	// IsQueued is true while the article is in the read-later queue. Unlike saving, it
+	// is cleared when the article is read.
+	IsQueued bool `json:"is_queued"`
+	// QueuedAt is when the article was added to the read-later queue; nil if not queued
+	QueuedAt *time.Time `json:"queued_at,omitempty"`
+	// ContentExtracted is true when Content was extracted from the article's web page
+	// rather than taken from the feed
+	ContentExtracted bool `json:"content_extracted"`
+	// Language is the article's ISO 639-1 language code, e.g. "en", or "" if unknown
+	Language string `json:"language,omitempty"`
+	// Note is the user's own note on the article, or "" if none
+	Note string `json:"note,omitempty"`
+	// ContentHash fingerprints the title and summary for content dedup. It is written
+	// when the article is stored but not loaded by queries.
+	ContentHash string `json:"-"`
 }
 
 // hashArticleID generates a unique ID for an article based on feed URL and entry GUID/link
@@ -42,34 +102,96 @@ func hashArticleID(feedURL, entryGUID string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-// UpsertFeed inserts or updates a feed in the database
+// UpsertFeed inserts or updates a feed in the database. New feeds are placed last in the display order.
+// Re-adding a deleted feed whose saved articles were kept brings it back with them.
 func UpsertFeed(db *sql.DB, feed *Feed) error {
 	query := `
-	INSERT INTO feeds (id, name, url, category, enabled, last_fetched_at)
-	VALUES (?, ?, ?, ?, ?, ?)
+	INSERT INTO feeds (id, name, url, category, enabled, last_fetched_at, display_order, id_url)
+	VALUES (?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(display_order), -1) + 1 FROM feeds), ?)
 	ON CONFLICT(id) DO UPDATE SET
 		name = excluded.name,
 		url = excluded.url,
 		category = excluded.category,
 		enabled = excluded.enabled,
-		last_fetched_at = excluded.last_fetched_at;`
+		last_fetched_at = excluded.last_fetched_at,
+		deleted = 0,
+		moved_to = CASE WHEN feeds.url = excluded.url THEN feeds.moved_to ELSE '' END,
+		id_url = CASE WHEN feeds.id_url = '' THEN excluded.url ELSE feeds.id_url END;`
 
-	_, err := db.Exec(query, feed.ID, feed.Name, feed.URL, feed.Category, feed.Enabled, feed.LastFetchedAt)
+	_, err := db.Exec(query, feed.ID, feed.Name, feed.URL, feed.Category, feed.Enabled, feed.LastFetchedAt, feed.URL)
 	if err != nil {
 		return fmt.Errorf("failed to upsert feed: %w", err)
 	}
 	return nil
 }
 
-// ListFeeds returns all feeds, optionally filtering by enabled status
+// UpsertFeedSettings inserts a feed or updates its name, URL, category and enabled
+// flag, leaving the fetch history of an existing feed untouched
+func UpsertFeedSettings(db *sql.DB, feed *Feed) error {
+	query := `
+	INSERT INTO feeds (id, name, url, category, enabled, display_order, id_url)
+	VALUES (?, ?, ?, ?, ?, (SELECT COALESCE(MAX(display_order), -1) + 1 FROM feeds), ?)
+	ON CONFLICT(id) DO UPDATE SET
+		name = excluded.name,
+		url = excluded.url,
+		category = excluded.category,
+		enabled = excluded.enabled,
+		deleted = 0,
+		moved_to = CASE WHEN feeds.url = excluded.url THEN feeds.moved_to ELSE '' END,
+		id_url = CASE WHEN feeds.id_url = '' THEN excluded.url ELSE feeds.id_url END;`
+
+	_, err := db.Exec(query, feed.ID, feed.Name, feed.URL, feed.Category, feed.Enabled, feed.URL)
+	if err != nil {
+		return fmt.Errorf("failed to upsert feed: %w", err)
+	}
+	return nil
+}
+
+// feedColumns is the column list shared by all feed SELECT queries
+const feedColumns = `id, name, url, category, enabled, last_fetched_at, last_attempt_at, last_success_at, last_error, consecutive_failures, moved_to, muted_until, ttl_minutes, skip_hours, skip_days, site_url, icon_url, id_url`
+
+// scanFeed scans a row selected with feedColumns into a Feed
+func scanFeed(row rowScanner) (*Feed, error) {
+	var f Feed
+	var lastFetched, lastAttempt, lastSuccess, mutedUntil sql.NullTime
+	var lastError sql.NullString
+	var skipHours, skipDays string
+	err := row.Scan(&f.ID, &f.Name, &f.URL, &f.Category, &f.Enabled, &lastFetched,
+		&lastAttempt, &lastSuccess, &lastError, &f.ConsecutiveFailures, &f.MovedTo, &mutedUntil,
+		&f.TTLMinutes, &skipHours, &skipDays, &f.SiteURL, &f.IconURL, &f.IDURL)
+	if err != nil {
+		return nil, err
+	}
+	f.SkipHours = splitInts(skipHours)
+	for _, d := range splitInts(skipDays) {
+		f.SkipDays = append(f.SkipDays, time.Weekday(d))
+	}
+	if lastFetched.Valid {
+		f.LastFetchedAt = &lastFetched.Time
+	}
+	if lastAttempt.Valid {
+		f.LastAttemptAt = &lastAttempt.Time
+	}
+	if lastSuccess.Valid {
+		f.LastSuccessAt = &lastSuccess.Time
+	}
+	if mutedUntil.Valid {
+		f.MutedUntil = &mutedUntil.Time
+	}
+	f.LastError = lastError.String
+	return &f, nil
+}
+
+// ListFeeds returns all feeds in display order, optionally filtering by enabled status.
+// Deleted feeds kept for their saved articles are left out.
 func ListFeeds(db *sql.DB, enabledOnly bool) ([]*Feed, error) {
 	var query string
 	var args []interface{}
 
 	if enabledOnly {
-		query = `SELECT id, name, url, category, enabled, last_fetched_at FROM feeds WHERE enabled = 1 ORDER BY name;`
+		query = `SELECT ` + feedColumns + ` FROM feeds WHERE enabled = 1 AND deleted = 0 ORDER BY display_order, name;`
 	} else {
-		query = `SELECT id, name, url, category, enabled, last_fetched_at FROM feeds ORDER BY name;`
+		query = `SELECT ` + feedColumns + ` FROM feeds WHERE deleted = 0 ORDER BY display_order, name;`
 	}
 
 	rows, err := db.Query(query, args...)
@@ -80,16 +202,11 @@ func ListFeeds(db *sql.DB, enabledOnly bool) ([]*Feed, error) {
 
 	var feeds []*Feed
 	for rows.Next() {
-		var f Feed
-		var lastFetched sql.NullTime
-		err := rows.Scan(&f.ID, &f.Name, &f.URL, &f.Category, &f.Enabled, &lastFetched)
+		f, err := scanFeed(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan feed: %w", err)
 		}
-		if lastFetched.Valid {
-			f.LastFetchedAt = &lastFetched.Time
-		}
-		feeds = append(feeds, &f)
+		feeds = append(feeds, f)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -99,23 +216,97 @@ func ListFeeds(db *sql.DB, enabledOnly bool) ([]*Feed, error) {
 	return feeds, nil
 }
 
-// GetFeedByID returns a feed by its ID
+// MoveFeed moves a feed offset places in the display order (negative is up), stopping at
+// either end. The order of all feeds is renumbered from 0 in the same transaction.
+func MoveFeed(db *sql.DB, feedID string, offset int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id FROM feeds WHERE deleted = 0 ORDER BY display_order, name;`)
+	if err != nil {
+		return fmt.Errorf("failed to query feed order: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan feed id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating feeds: %w", err)
+	}
+
+	from := -1
+	for i, id := range ids {
+		if id == feedID {
+			from = i
+			break
+		}
+	}
+	if from < 0 {
+		return fmt.Errorf("feed %s not found", feedID)
+	}
+	to := min(max(from+offset, 0), len(ids)-1)
+	ids = append(ids[:from], ids[from+1:]...)
+	ids = append(ids[:to], append([]string{feedID}, ids[to:]...)...)
+
+	for i, id := range ids {
+		if _, err := tx.Exec(`UPDATE feeds SET display_order = ? WHERE id = ?;`, i, id); err != nil {
+			return fmt.Errorf("failed to update feed order: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListCategories returns the distinct non-empty feed categories, sorted by name
+func ListCategories(db *sql.DB) ([]string, error) {
+	query := `SELECT DISTINCT category FROM feeds WHERE category != '' AND deleted = 0 ORDER BY category;`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []string
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// GetFeedByID returns a feed by its ID. Deleted feeds aren't found.
 func GetFeedByID(db *sql.DB, id string) (*Feed, error) {
-	query := `SELECT id, name, url, category, enabled, last_fetched_at FROM feeds WHERE id = ?;`
+	query := `SELECT ` + feedColumns + ` FROM feeds WHERE id = ? AND deleted = 0;`
 
-	var f Feed
-	var lastFetched sql.NullTime
-	err := db.QueryRow(query, id).Scan(&f.ID, &f.Name, &f.URL, &f.Category, &f.Enabled, &lastFetched)
+	f, err := scanFeed(db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("feed not found: %s", id)
 		}
 		return nil, fmt.Errorf("failed to get feed: %w", err)
 	}
-	if lastFetched.Valid {
-		f.LastFetchedAt = &lastFetched.Time
-	}
-	return &f, nil
+	return f, nil
 }
 
 // UpdateFeedLastFetched updates the last_fetched_at timestamp for a feed
@@ -128,24 +319,179 @@ func UpdateFeedLastFetched(db *sql.DB, feedID string, t time.Time) error {
 	return nil
 }
 
-// UpsertArticle inserts or updates an article in the database
-func UpsertArticle(db *sql.DB, article *Article) error {
-	query := `
-	INSERT INTO articles (id, feed_id, title, url, summary, content, published_at, fetched_at, source_name, categories, is_read, is_saved, is_trashed)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+// SetFeedMovedTo records the URL a feed has permanently moved to; an empty url clears it
+func SetFeedMovedTo(db *sql.DB, feedID string, url string) error {
+	_, err := db.Exec(`UPDATE feeds SET moved_to = ? WHERE id = ?;`, url, feedID)
+	if err != nil {
+		return fmt.Errorf("failed to update feed moved_to: %w", err)
+	}
+	return nil
+}
+
+// SetFeedMutedUntil mutes a feed until the given time; a zero time unmutes it
+func SetFeedMutedUntil(db *sql.DB, feedID string, until time.Time) error {
+	var value interface{}
+	if !until.IsZero() {
+		value = until
+	}
+	_, err := db.Exec(`UPDATE feeds SET muted_until = ? WHERE id = ?;`, value, feedID)
+	if err != nil {
+		return fmt.Errorf("failed to update feed muted_until: %w", err)
+	}
+	return nil
+}
+
+// SetFeedScheduleHints stores the polling hints a feed published on its latest fetch
+func SetFeedScheduleHints(db *sql.DB, feedID string, ttlMinutes int, skipHours []int, skipDays []time.Weekday) error {
+	days := make([]int, len(skipDays))
+	for i, d := range skipDays {
+		days[i] = int(d)
+	}
+	_, err := db.Exec(`UPDATE feeds SET ttl_minutes = ?, skip_hours = ?, skip_days = ? WHERE id = ?;`,
+		ttlMinutes, joinInts(skipHours), joinInts(days), feedID)
+	if err != nil {
+		return fmt.Errorf("failed to update feed schedule hints: %w", err)
+	}
+	return nil
+}
+
+// SetFeedSiteInfo stores the website and favicon URLs of a feed
+func SetFeedSiteInfo(db *sql.DB, feedID string, siteURL string, iconURL string) error {
+	_, err := db.Exec(`UPDATE feeds SET site_url = ?, icon_url = ? WHERE id = ?;`, siteURL, iconURL, feedID)
+	if err != nil {
+		return fmt.Errorf("failed to update feed site info: %w", err)
+	}
+	return nil
+}
+
+// joinInts encodes numbers as a comma-separated list, e.g. "1,2,3"
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+// splitInts decodes a list written by joinInts, skipping malformed entries
+func splitInts(s string) []int {
+	var values []int
+	for _, part := range strings.Split(s, ",") {
+		if v, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// RecordFeedFetchResult stores the outcome and time of a fetch attempt. A nil fetchErr clears the
+// error state and records a successful fetch; otherwise the error is stored and the
+// consecutive failure count is incremented.
+func RecordFeedFetchResult(db *sql.DB, feedID string, fetchErr error) error {
+	var err error
+	now := time.Now()
+	if fetchErr == nil {
+		_, err = db.Exec(`UPDATE feeds SET last_attempt_at = ?, last_success_at = ?, last_error = NULL, consecutive_failures = 0 WHERE id = ?;`,
+			now, now, feedID)
+	} else {
+		_, err = db.Exec(`UPDATE feeds SET last_attempt_at = ?, last_error = ?, consecutive_failures = consecutive_failures + 1 WHERE id = ?;`,
+			now, fetchErr.Error(), feedID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record feed fetch result: %w", err)
+	}
+	return nil
+}
+
+// DeleteFeed removes a feed and its articles. If keepSaved is true, saved articles from the
+// feed are kept, and so is the feed's row, marked deleted, so they still belong to a feed.
+// A deleted feed is hidden from feed queries until it is added again.
+func DeleteFeed(db *sql.DB, feedID string, keepSaved bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	articlesQuery := `DELETE FROM articles WHERE feed_id = ?`
+	if keepSaved {
+		articlesQuery += ` AND is_saved = 0`
+	}
+	if _, err := tx.Exec(articlesQuery+`;`, feedID); err != nil {
+		return fmt.Errorf("failed to delete feed articles: %w", err)
+	}
+
+	// Keep the row only while articles still reference it
+	if _, err := tx.Exec(`UPDATE feeds SET deleted = 1, enabled = 0 WHERE id = ?;`, feedID); err != nil {
+		return fmt.Errorf("failed to delete feed: %w", err)
+	}
+	if err := deleteUnusedFeeds(tx, feedID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit feed deletion: %w", err)
+	}
+	return nil
+}
+
+// deleteUnusedFeeds removes deleted feeds that no articles reference anymore. With a
+// feedID, only that feed is considered.
+func deleteUnusedFeeds(tx *sql.Tx, feedID string) error {
+	query := `DELETE FROM feeds WHERE deleted = 1
+		AND NOT EXISTS (SELECT 1 FROM articles WHERE articles.feed_id = feeds.id)`
+	var args []interface{}
+	if feedID != "" {
+		query += ` AND id = ?`
+		args = append(args, feedID)
+	}
+	if _, err := tx.Exec(query+`;`, args...); err != nil {
+		return fmt.Errorf("failed to delete unused feeds: %w", err)
+	}
+	return nil
+}
+
+// DeleteUnusedFeeds removes deleted feeds whose kept articles are all gone, for example
+// because they were unsaved and then expired
+func DeleteUnusedFeeds(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := deleteUnusedFeeds(tx, ""); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// upsertArticleQuery inserts an article or merges a re-fetched one into the stored row,
+// keeping its fetch time, read/saved/trashed state and any extracted full text
+const upsertArticleQuery = `
+	INSERT INTO articles (id, feed_id, title, url, summary, content, published_at, fetched_at, source_name, categories, image_url, is_read, is_saved, is_trashed, language, content_hash)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	ON CONFLICT(id) DO UPDATE SET
 		title = excluded.title,
 		url = excluded.url,
 		summary = excluded.summary,
-		content = excluded.content,
+		content = CASE WHEN articles.content_extracted = 1 THEN articles.content ELSE excluded.content END,
 		published_at = excluded.published_at,
 		fetched_at = COALESCE(articles.fetched_at, excluded.fetched_at),
 		source_name = excluded.source_name,
 		categories = excluded.categories,
-		is_read = COALESCE(excluded.is_read, articles.is_read),
-		is_saved = COALESCE(excluded.is_saved, articles.is_saved),
-		is_trashed = MAX(articles.is_trashed, excluded.is_trashed);`
+		image_url = excluded.image_url,
+		is_read = MAX(articles.is_read, excluded.is_read),
+		is_saved = MAX(articles.is_saved, excluded.is_saved),
+		is_trashed = MAX(articles.is_trashed, excluded.is_trashed),
+		language = excluded.language,
+		content_hash = excluded.content_hash;`
 
+// upsertArticleArgs returns the arguments for upsertArticleQuery
+func upsertArticleArgs(article *Article) []interface{} {
 	isRead := 0
 	if article.IsRead {
 		isRead = 1
@@ -159,72 +505,233 @@ func UpsertArticle(db *sql.DB, article *Article) error {
 		isTrashed = 1
 	}
 
-	_, err := db.Exec(query,
+	return []interface{}{
 		article.ID, article.FeedID, article.Title, article.URL, article.Summary,
 		article.Content, article.PublishedAt, article.FetchedAt, article.SourceName,
-		article.Categories, isRead, isSaved, isTrashed)
+		article.Categories, article.ImageURL, isRead, isSaved, isTrashed, article.Language,
+		article.ContentHash,
+	}
+}
+
+// UpsertArticle inserts or updates an article in the database
+func UpsertArticle(db *sql.DB, article *Article) error {
+	_, err := db.Exec(upsertArticleQuery, upsertArticleArgs(article)...)
 	if err != nil {
 		return fmt.Errorf("failed to upsert article: %w", err)
 	}
 	return nil
 }
 
-// ListArticlesByView returns articles based on view type and optional feed filter
-// readFilter can be "all", "unread", or "read"
-func ListArticlesByView(db *sql.DB, view string, feedID string, readFilter string, limit int) ([]*Article, error) {
-	var query string
+// UpsertArticles inserts or updates a batch of articles in a single transaction, which
+// is much faster than one UpsertArticle call per article. Either all articles are
+// stored or, on error, none are.
+func UpsertArticles(db *sql.DB, articles []*Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(upsertArticleQuery)
+	if err != nil {
+		return fmt.Errorf("failed to prepare article upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, article := range articles {
+		if _, err := stmt.Exec(upsertArticleArgs(article)...); err != nil {
+			return fmt.Errorf("failed to upsert article %s: %w", article.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit articles: %w", err)
+	}
+	return nil
+}
+
+// articleColumns is the column list shared by all article SELECT queries
+const articleColumns = `id, feed_id, title, url, summary, content, published_at, fetched_at, source_name, categories, image_url, is_read, is_saved, is_trashed, read_at, saved_at, content_extracted, language, note, is_queued, queued_at`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanArticle scans a row selected with articleColumns into an Article
+func scanArticle(row rowScanner) (*Article, error) {
+	var a Article
+	var isRead, isSaved, isTrashed, contentExtracted, isQueued int
+	var readAt, savedAt, queuedAt sql.NullTime
+	var note sql.NullString
+	err := row.Scan(&a.ID, &a.FeedID, &a.Title, &a.URL, &a.Summary, &a.Content,
+		&a.PublishedAt, &a.FetchedAt, &a.SourceName, &a.Categories, &a.ImageURL, &isRead, &isSaved, &isTrashed,
+		&readAt, &savedAt, &contentExtracted, &a.Language, &note, &isQueued, &queuedAt)
+	if err != nil {
+		return nil, err
+	}
+	if readAt.Valid {
+		a.ReadAt = &readAt.Time
+	}
+	if savedAt.Valid {
+		a.SavedAt = &savedAt.Time
+	}
+	if queuedAt.Valid {
+		a.QueuedAt = &queuedAt.Time
+	}
+	a.IsRead = isRead == 1
+	a.IsSaved = isSaved == 1
+	a.IsTrashed = isTrashed == 1
+	a.IsQueued = isQueued == 1
+	a.ContentExtracted = contentExtracted == fullTextExtracted
+	a.Note = note.String
+	return &a, nil
+}
+
+// ArticleFilter selects the articles shown by a view. Empty or "all" FeedID and
+// Category match every feed; ReadFilter can be "all", "unread", or "read".
+// A non-zero Window replaces the built-in time window of the latest, today and week
+// views. A non-zero From or To restricts published_at to [From, To) and replaces the
+// time window altogether. Sort orders the latest, today and week views; saved and
+// history keep their own order.
+type ArticleFilter struct {
+	View       string
+	FeedID     string
+	Category   string
+	ReadFilter string
+	Window     time.Duration
+	From       time.Time
+	To         time.Time
+	Sort       string
+	// Location is the time zone the today view's day starts in; nil means time.Local
+	Location *time.Location
+}
+
+// Sort orders for ArticleFilter.Sort. An empty Sort means SortUnreadFirst.
+const (
+	SortUnreadFirst = "unread-first"
+	SortNewest      = "newest"
+	SortOldest      = "oldest"
+)
+
+// articleViewFilter builds the WHERE clause and arguments for an article filter
+func articleViewFilter(f ArticleFilter) (string, []interface{}) {
+	var where string
 	var args []interface{}
 
 	now := time.Now()
 	var timeWindow time.Time
 
-	switch view {
+	switch f.View {
 	case "saved":
 		// Saved articles view - no time window, just saved articles
-		query = `SELECT id, feed_id, title, url, summary, content, published_at, fetched_at, source_name, categories, is_read, is_saved, is_trashed
-			FROM articles
-			WHERE is_saved = 1 AND is_trashed = 0`
-		// No time window for saved articles
+		where = ` WHERE is_saved = 1 AND is_trashed = 0`
+	case "history":
+		// Recently read view - articles the user marked read, whenever published
+		where = ` WHERE read_at IS NOT NULL AND is_read = 1 AND is_trashed = 0`
+	case "queue":
+		// Read-later view - queued articles, whenever published
+		where = ` WHERE is_queued = 1 AND is_trashed = 0`
 	case "today":
 		// Start of today
-		timeWindow = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-		query = `SELECT id, feed_id, title, url, summary, content, published_at, fetched_at, source_name, categories, is_read, is_saved, is_trashed
-			FROM articles
-			WHERE published_at >= ? AND is_trashed = 0`
+		loc := f.Location
+		if loc == nil {
+			loc = time.Local
+		}
+		today := now.In(loc)
+		timeWindow = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
+		where = ` WHERE published_at >= ? AND is_trashed = 0`
 	case "week":
 		// Last 7 days
 		timeWindow = now.AddDate(0, 0, -7)
-		query = `SELECT id, feed_id, title, url, summary, content, published_at, fetched_at, source_name, categories, is_read, is_saved, is_trashed
-			FROM articles
-			WHERE published_at >= ? AND is_trashed = 0`
+		where = ` WHERE published_at >= ? AND is_trashed = 0`
 	case "latest":
 		fallthrough
 	default:
 		// Last 3 days or just limit
 		timeWindow = now.AddDate(0, 0, -3)
-		query = `SELECT id, feed_id, title, url, summary, content, published_at, fetched_at, source_name, categories, is_read, is_saved, is_trashed
-			FROM articles
-			WHERE published_at >= ? AND is_trashed = 0`
+		where = ` WHERE published_at >= ? AND is_trashed = 0`
 	}
 
-	if view != "saved" {
-		args = append(args, timeWindow)
+	hasRange := !f.From.IsZero() || !f.To.IsZero()
+	if f.View != "saved" && f.View != "history" && f.View != "queue" {
+		if f.Window > 0 {
+			timeWindow = now.Add(-f.Window)
+		}
+		if hasRange {
+			// An explicit date range replaces the view's time window
+			where = ` WHERE is_trashed = 0`
+		} else {
+			// Times are compared as RFC 3339 text, and published_at is stored in UTC
+			args = append(args, timeWindow.UTC())
+		}
+	}
+	if !f.From.IsZero() {
+		where += ` AND published_at >= ?`
+		args = append(args, f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		where += ` AND published_at < ?`
+		args = append(args, f.To.UTC())
 	}
 
-	if feedID != "" && feedID != "all" {
-		query += ` AND feed_id = ?`
-		args = append(args, feedID)
+	if f.FeedID != "" && f.FeedID != "all" {
+		where += ` AND feed_id = ?`
+		args = append(args, f.FeedID)
+	}
+
+	if f.Category != "" && f.Category != "all" {
+		where += ` AND feed_id IN (SELECT id FROM feeds WHERE category = ?)`
+		args = append(args, f.Category)
 	}
 
 	// Add read filter
-	if readFilter == "unread" {
-		query += ` AND is_read = 0`
-	} else if readFilter == "read" {
-		query += ` AND is_read = 1`
+	if f.ReadFilter == "unread" {
+		where += ` AND is_read = 0`
+	} else if f.ReadFilter == "read" {
+		where += ` AND is_read = 1`
 	}
 
-	// Sort: unread first (by published_at DESC), then read (by published_at DESC)
-	query += ` ORDER BY is_read ASC, published_at DESC LIMIT ?;`
+	return where, args
+}
+
+// articleViewQuery builds the SELECT query and arguments for an article filter.
+// The returned query has no ORDER BY or LIMIT clause.
+func articleViewQuery(f ArticleFilter) (string, []interface{}) {
+	where, args := articleViewFilter(f)
+	return `SELECT ` + articleColumns + ` FROM articles` + where, args
+}
+
+// ListArticlesByView returns up to limit articles matching the filter
+func ListArticlesByView(db *sql.DB, f ArticleFilter, limit int) ([]*Article, error) {
+	query, args := articleViewQuery(f)
+
+	switch f.View {
+	case "history":
+		// Most recently read first
+		query += ` ORDER BY read_at DESC LIMIT ?;`
+	case "saved":
+		// Most recently saved first; id breaks ties so NextUnreadArticle follows the same order
+		query += ` ORDER BY saved_at DESC, published_at DESC, id ASC LIMIT ?;`
+	case "queue":
+		// First queued first, so the queue is read in the order it was filled
+		query += ` ORDER BY queued_at ASC, id ASC LIMIT ?;`
+	default:
+		switch f.Sort {
+		case SortNewest:
+			query += ` ORDER BY published_at DESC, id ASC LIMIT ?;`
+		case SortOldest:
+			// Oldest first, for catching up in the order stories were published
+			query += ` ORDER BY published_at ASC, id ASC LIMIT ?;`
+		default:
+			// Sort: unread first (by published_at DESC), then read (by published_at DESC)
+			query += ` ORDER BY is_read ASC, published_at DESC, id ASC LIMIT ?;`
+		}
+	}
 	args = append(args, limit)
 
 	rows, err := db.Query(query, args...)
@@ -235,17 +742,11 @@ func ListArticlesByView(db *sql.DB, view string, feedID string, readFilter strin
 
 	var articles []*Article
 	for rows.Next() {
-		var a Article
-		var isRead, isSaved, isTrashed int
-		err := rows.Scan(&a.ID, &a.FeedID, &a.Title, &a.URL, &a.Summary, &a.Content,
-			&a.PublishedAt, &a.FetchedAt, &a.SourceName, &a.Categories, &isRead, &isSaved, &isTrashed)
+		a, err := scanArticle(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan article: %w", err)
 		}
-		a.IsRead = isRead == 1
-		a.IsSaved = isSaved == 1
-		a.IsTrashed = isTrashed == 1
-		articles = append(articles, &a)
+		articles = append(articles, a)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -255,19 +756,257 @@ func ListArticlesByView(db *sql.DB, view string, feedID string, readFilter strin
 	return articles, nil
 }
 
-// MarkArticleAsRead marks an article as read
+// NextUnreadArticle returns the first unread article matching the filter that comes after
+// the given article in the view's list order, or the first unread article if after is nil.
+// It returns ErrArticleNotFound when there are no more unread articles. The blocklist is
+// not applied.
+func NextUnreadArticle(db *sql.DB, f ArticleFilter, after *Article) (*Article, error) {
+	f.ReadFilter = "unread"
+	query, args := articleViewQuery(f)
+
+	if f.View == "saved" {
+		if after != nil {
+			query += ` AND (saved_at < ? OR (saved_at = ? AND (published_at < ? OR (published_at = ? AND id > ?))))`
+			args = append(args, after.SavedAt, after.SavedAt, after.PublishedAt, after.PublishedAt, after.ID)
+		}
+		query += ` ORDER BY saved_at DESC, published_at DESC, id ASC LIMIT 1;`
+	} else if f.View == "queue" {
+		if after != nil {
+			query += ` AND (queued_at > ? OR (queued_at = ? AND id > ?))`
+			args = append(args, after.QueuedAt, after.QueuedAt, after.ID)
+		}
+		query += ` ORDER BY queued_at ASC, id ASC LIMIT 1;`
+	} else if f.View != "history" && f.Sort == SortOldest {
+		if after != nil {
+			query += ` AND (published_at > ? OR (published_at = ? AND id > ?))`
+			args = append(args, after.PublishedAt, after.PublishedAt, after.ID)
+		}
+		query += ` ORDER BY published_at ASC, id ASC LIMIT 1;`
+	} else {
+		if after != nil {
+			query += ` AND (published_at < ? OR (published_at = ? AND id > ?))`
+			args = append(args, after.PublishedAt, after.PublishedAt, after.ID)
+		}
+		query += ` ORDER BY published_at DESC, id ASC LIMIT 1;`
+	}
+
+	a, err := scanArticle(db.QueryRow(query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrArticleNotFound
+		}
+		return nil, fmt.Errorf("failed to query next unread article: %w", err)
+	}
+	return a, nil
+}
+
+// ListRecentlyRead returns up to limit articles the user has read, most recently read first
+func ListRecentlyRead(db *sql.DB, limit int) ([]*Article, error) {
+	return ListArticlesByView(db, ArticleFilter{View: "history"}, limit)
+}
+
+// CountArticlesByView returns how many articles match the filter and how many of those are
+// unread, in a single query. Counts are computed before blocklist filtering.
+func CountArticlesByView(db *sql.DB, f ArticleFilter) (total int, unread int, err error) {
+	where, args := articleViewFilter(f)
+	query := `SELECT COUNT(*), COALESCE(SUM(is_read = 0), 0) FROM articles` + where + `;`
+
+	if err := db.QueryRow(query, args...).Scan(&total, &unread); err != nil {
+		return 0, 0, fmt.Errorf("failed to count articles: %w", err)
+	}
+	return total, unread, nil
+}
+
+// UnreadCountsByFeed returns the number of unread articles per feed ID within the "latest" time window,
+// overridden by latestWindow if non-zero. Counts are computed before blocklist filtering, so they
+// may include articles hidden in the UI.
+func UnreadCountsByFeed(db *sql.DB, latestWindow time.Duration) (map[string]int, error) {
+	where, args := articleViewFilter(ArticleFilter{View: "latest", ReadFilter: "unread", Window: latestWindow})
+	query := `SELECT feed_id, COUNT(*) FROM articles` + where + ` GROUP BY feed_id;`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unread counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var feedID string
+		var count int
+		if err := rows.Scan(&feedID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan unread count: %w", err)
+		}
+		counts[feedID] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating unread counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// Stats holds database totals for health checks and metrics
+type Stats struct {
+	Feeds          int
+	EnabledFeeds   int
+	FailingFeeds   int
+	Articles       int
+	UnreadArticles int
+	// LastSuccessAt is the most recent successful fetch of any feed, nil if none has succeeded
+	LastSuccessAt *time.Time
+}
+
+// GetStats returns feed and article totals using a few aggregate queries
+func GetStats(db *sql.DB) (*Stats, error) {
+	var st Stats
+	err := db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(enabled = 1), 0), COALESCE(SUM(consecutive_failures > 0), 0) FROM feeds WHERE deleted = 0;`).
+		Scan(&st.Feeds, &st.EnabledFeeds, &st.FailingFeeds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count feeds: %w", err)
+	}
+
+	err = db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(is_read = 0), 0) FROM articles;`).Scan(&st.Articles, &st.UnreadArticles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count articles: %w", err)
+	}
+
+	// Select the column itself rather than MAX() so the driver still parses it as a time
+	var lastSuccess sql.NullTime
+	err = db.QueryRow(`SELECT last_success_at FROM feeds WHERE last_success_at IS NOT NULL ORDER BY last_success_at DESC LIMIT 1;`).Scan(&lastSuccess)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to query last successful fetch: %w", err)
+	}
+	if lastSuccess.Valid {
+		st.LastSuccessAt = &lastSuccess.Time
+	}
+
+	return &st, nil
+}
+
+// IterateArticlesByView streams articles matching the filter to fn, one row at a
+// time, without loading the whole result set into memory.
+// Iteration stops at the first error returned by fn.
+func IterateArticlesByView(db *sql.DB, f ArticleFilter, fn func(*Article) error) error {
+	query, args := articleViewQuery(f)
+	query += ` ORDER BY published_at DESC;`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query articles: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a, err := scanArticle(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan article: %w", err)
+		}
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating articles: %w", err)
+	}
+
+	return nil
+}
+
+// GetArticleByID returns an article by its ID, or ErrArticleNotFound if it doesn't exist
+func GetArticleByID(db *sql.DB, id string) (*Article, error) {
+	query := `SELECT ` + articleColumns + ` FROM articles WHERE id = ?;`
+
+	a, err := scanArticle(db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrArticleNotFound
+		}
+		return nil, fmt.Errorf("failed to get article: %w", err)
+	}
+	return a, nil
+}
+
+// MarkArticleAsRead marks an article as read, removing it from the read-later queue
 func MarkArticleAsRead(db *sql.DB, articleID string) error {
-	query := `UPDATE articles SET is_read = 1 WHERE id = ?;`
-	_, err := db.Exec(query, articleID)
+	// Already-read articles keep their original read_at, so repeated marks are harmless
+	query := `UPDATE articles SET is_read = 1, read_at = ?, is_queued = 0, queued_at = NULL WHERE id = ? AND is_read = 0;`
+	_, err := db.Exec(query, time.Now(), articleID)
 	if err != nil {
 		return fmt.Errorf("failed to mark article as read: %w", err)
 	}
 	return nil
 }
 
+// MarkArticlesAsRead marks the given articles as read in a single transaction and returns
+// how many were updated, removing them from the read-later queue. Articles that are
+// already read or don't exist are skipped, so their read_at is left alone.
+func MarkArticlesAsRead(db *sql.DB, articleIDs []string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`UPDATE articles SET is_read = 1, read_at = ?, is_queued = 0, queued_at = NULL WHERE id = ? AND is_read = 0;`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	var updated int64
+	for _, id := range articleIDs {
+		result, err := stmt.Exec(now, id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to mark article as read: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		updated += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return updated, nil
+}
+
+// MarkAllAsRead marks every unread article matching the filter as read and returns
+// the number of articles updated, removing them from the read-later queue. The filter's
+// ReadFilter is ignored.
+func MarkAllAsRead(db *sql.DB, f ArticleFilter) (int64, error) {
+	f.ReadFilter = "unread"
+	where, args := articleViewFilter(f)
+	query := `UPDATE articles SET is_read = 1, read_at = ?, is_queued = 0, queued_at = NULL` + where + `;`
+
+	result, err := db.Exec(query, append([]interface{}{time.Now()}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark articles as read: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return updated, nil
+}
+
+// MarkFeedArticlesRead marks a feed's unread articles within the latest view's window as
+// read and returns the number updated, clearing the feed's unread badge. A positive window
+// overrides the view's built-in one. Saved articles are marked read and stay saved.
+func MarkFeedArticlesRead(db *sql.DB, feedID string, window time.Duration) (int64, error) {
+	return MarkAllAsRead(db, ArticleFilter{View: "latest", FeedID: feedID, Window: window})
+}
+
 // MarkArticleAsUnread marks an article as unread
 func MarkArticleAsUnread(db *sql.DB, articleID string) error {
-	query := `UPDATE articles SET is_read = 0 WHERE id = ?;`
+	query := `UPDATE articles SET is_read = 0, read_at = NULL WHERE id = ?;`
 	_, err := db.Exec(query, articleID)
 	if err != nil {
 		return fmt.Errorf("failed to mark article as unread: %w", err)
@@ -275,16 +1014,75 @@ func MarkArticleAsUnread(db *sql.DB, articleID string) error {
 	return nil
 }
 
-// ToggleArticleSaved toggles the saved status of an article
+// ToggleArticleRead flips an article's read status and returns the new value, or
+// ErrArticleNotFound if there is no such article. As with MarkArticleAsRead, reading an
+// article takes it out of the read-later queue.
+func ToggleArticleRead(db *sql.DB, articleID string) (bool, error) {
+	// The CASEs see the old is_read value, so 0 means the article is being read now
+	query := `UPDATE articles SET
+		read_at = CASE WHEN is_read = 0 THEN ? ELSE NULL END,
+		is_queued = CASE WHEN is_read = 0 THEN 0 ELSE is_queued END,
+		queued_at = CASE WHEN is_read = 0 THEN NULL ELSE queued_at END,
+		is_read = NOT is_read
+		WHERE id = ?
+		RETURNING is_read;`
+	var isRead int
+	err := db.QueryRow(query, time.Now(), articleID).Scan(&isRead)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrArticleNotFound
+		}
+		return false, fmt.Errorf("failed to toggle article read status: %w", err)
+	}
+	return isRead == 1, nil
+}
+
+// ToggleArticleSaved toggles the saved status of an article. Saving records the time in
+// saved_at (so re-saving moves it to the top of the saved view); unsaving clears it.
 func ToggleArticleSaved(db *sql.DB, articleID string) error {
-	query := `UPDATE articles SET is_saved = NOT is_saved WHERE id = ?;`
-	_, err := db.Exec(query, articleID)
+	// The CASE sees the old is_saved value, so 0 means the article is being saved now
+	query := `UPDATE articles SET
+		is_saved = NOT is_saved,
+		saved_at = CASE WHEN is_saved = 0 THEN ? ELSE NULL END
+		WHERE id = ?;`
+	_, err := db.Exec(query, time.Now(), articleID)
 	if err != nil {
 		return fmt.Errorf("failed to toggle article saved status: %w", err)
 	}
 	return nil
 }
 
+// SetArticleNote sets the user's note on an article. An empty note removes it.
+func SetArticleNote(db *sql.DB, articleID string, note string) error {
+	var value interface{}
+	if note != "" {
+		value = note
+	}
+	result, err := db.Exec(`UPDATE articles SET note = ? WHERE id = ?;`, value, articleID)
+	if err != nil {
+		return fmt.Errorf("failed to set article note: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrArticleNotFound
+	}
+	return nil
+}
+
+// ToggleArticleQueued adds an article to the read-later queue or removes it. Its read
+// status is left alone.
+func ToggleArticleQueued(db *sql.DB, articleID string) error {
+	// The CASE sees the old is_queued value, so 0 means the article is being queued now
+	query := `UPDATE articles SET
+		queued_at = CASE WHEN is_queued = 0 THEN ? ELSE NULL END,
+		is_queued = NOT is_queued
+		WHERE id = ?;`
+	_, err := db.Exec(query, time.Now(), articleID)
+	if err != nil {
+		return fmt.Errorf("failed to toggle article queued status: %w", err)
+	}
+	return nil
+}
+
 // TrashArticle marks an article as trashed and returns its URL for blocklisting
 func TrashArticle(db *sql.DB, articleID string) (string, error) {
 	var url string
@@ -299,13 +1097,24 @@ func TrashArticle(db *sql.DB, articleID string) (string, error) {
 	return url, nil
 }
 
-// DeleteExpiredArticles deletes articles older than expirationHours from fetched_at, except saved ones
-func DeleteExpiredArticles(db *sql.DB, expirationHours int) (int64, error) {
+// DeleteExpiredArticles deletes articles older than expirationHours from fetched_at, except saved
+// or queued ones and those belonging to any of exemptFeedIDs
+func DeleteExpiredArticles(db *sql.DB, expirationHours int, exemptFeedIDs []string) (int64, error) {
 	query := `DELETE FROM articles 
 		WHERE is_saved = 0 
-		AND datetime(fetched_at, '+' || ? || ' hours') < datetime('now');`
+		AND is_queued = 0
+		AND datetime(fetched_at, '+' || ? || ' hours') < datetime('now')`
+	args := []interface{}{expirationHours}
+
+	if len(exemptFeedIDs) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(exemptFeedIDs)), ", ")
+		query += ` AND feed_id NOT IN (` + placeholders + `)`
+		for _, id := range exemptFeedIDs {
+			args = append(args, id)
+		}
+	}
 	
-	result, err := db.Exec(query, expirationHours)
+	result, err := db.Exec(query+";", args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete expired articles: %w", err)
 	}
@@ -318,19 +1127,177 @@ func DeleteExpiredArticles(db *sql.DB, expirationHours int) (int64, error) {
 	return deleted, nil
 }
 
+// content_extracted values: full text not attempted yet, extracted, or extraction failed
+const (
+	fullTextPending   = 0
+	fullTextExtracted = 1
+	fullTextFailed    = -1
+)
+
+// PurgeNonSavedArticles deletes every article that isn't saved or in the read-later
+// queue, however recently it was fetched, and returns how many were deleted
+func PurgeNonSavedArticles(db *sql.DB) (int64, error) {
+	result, err := db.Exec(`DELETE FROM articles WHERE is_saved = 0 AND is_queued = 0;`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge articles: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// ListArticlesPendingFullText returns up to limit of a feed's articles, newest first, whose
+// full text hasn't been extracted or attempted yet
+func ListArticlesPendingFullText(db *sql.DB, feedID string, limit int) ([]*Article, error) {
+	query := `SELECT ` + articleColumns + ` FROM articles
+		WHERE feed_id = ? AND content_extracted = ? AND is_trashed = 0 AND url != ''
+		ORDER BY published_at DESC LIMIT ?;`
+
+	rows, err := db.Query(query, feedID, fullTextPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query articles pending full text: %w", err)
+	}
+	defer rows.Close()
+
+	var articles []*Article
+	for rows.Next() {
+		a, err := scanArticle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan article: %w", err)
+		}
+		articles = append(articles, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating articles: %w", err)
+	}
+
+	return articles, nil
+}
+
+// SetArticleFullText replaces an article's content with the full text extracted from its page
+func SetArticleFullText(db *sql.DB, articleID string, content string) error {
+	_, err := db.Exec(`UPDATE articles SET content = ?, content_extracted = ? WHERE id = ?;`,
+		content, fullTextExtracted, articleID)
+	if err != nil {
+		return fmt.Errorf("failed to store article full text: %w", err)
+	}
+	return nil
+}
+
+// MarkFullTextFailed records that full-text extraction failed for an article so it isn't retried
+func MarkFullTextFailed(db *sql.DB, articleID string) error {
+	_, err := db.Exec(`UPDATE articles SET content_extracted = ? WHERE id = ?;`, fullTextFailed, articleID)
+	if err != nil {
+		return fmt.Errorf("failed to mark article full text failed: %w", err)
+	}
+	return nil
+}
+
+// TrimFeedArticles deletes a feed's unsaved articles beyond the max most recently published
+// and returns how many were deleted. Saved and queued articles are neither deleted nor counted.
+func TrimFeedArticles(db *sql.DB, feedID string, max int) (int64, error) {
+	query := `DELETE FROM articles
+		WHERE feed_id = ?
+		AND is_saved = 0 AND is_queued = 0
+		AND id NOT IN (
+			SELECT id FROM articles
+			WHERE feed_id = ? AND is_saved = 0 AND is_queued = 0
+			ORDER BY published_at DESC
+			LIMIT ?
+		);`
+
+	result, err := db.Exec(query, feedID, feedID, max)
+	if err != nil {
+		return 0, fmt.Errorf("failed to trim feed articles: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// MarkOldUnreadRead marks unread articles published more than olderThan ago as read, except saved
+// or queued ones.
+// read_at is left unset since the user never actually read them.
+func MarkOldUnreadRead(db *sql.DB, olderThan time.Duration) (int64, error) {
+	query := `UPDATE articles SET is_read = 1
+		WHERE is_read = 0
+		AND is_saved = 0
+		AND is_queued = 0
+		AND published_at < ?;`
+
+	// published_at is stored in UTC and compared as text, so the cutoff must be UTC too
+	result, err := db.Exec(query, time.Now().Add(-olderThan).UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark old articles as read: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return updated, nil
+}
+
 // GenerateArticleID generates an article ID from feed URL and entry GUID/link
 func GenerateArticleID(feedURL, entryGUID string) string {
 	return hashArticleID(feedURL, entryGUID)
 }
 
-// ArticleExistsByTitle checks if an article with the given title already exists in the database
-func ArticleExistsByTitle(db *sql.DB, title string) (bool, error) {
-	query := `SELECT COUNT(*) FROM articles WHERE title = ?;`
+// ArticleExistsByTitle checks if an article other than excludeID with the given title
+// already exists in the database. If feedID is non-empty, only that feed's articles
+// are checked.
+func ArticleExistsByTitle(db *sql.DB, title string, excludeID string, feedID string) (bool, error) {
+	query := `SELECT COUNT(*) FROM articles WHERE title = ? AND id != ?`
+	args := []interface{}{title, excludeID}
+	if feedID != "" {
+		query += ` AND feed_id = ?`
+		args = append(args, feedID)
+	}
 	var count int
-	err := db.QueryRow(query, title).Scan(&count)
+	err := db.QueryRow(query, args...).Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("failed to check article by title: %w", err)
 	}
 	return count > 0, nil
 }
 
+// AnyArticleExists checks if any of the articles with the given IDs is already stored
+func AnyArticleExists(db *sql.DB, ids []string) (bool, error) {
+	if len(ids) == 0 {
+		return false, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM articles WHERE id IN (`+placeholders+`)`, args...).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check articles by ID: %w", err)
+	}
+	return count > 0, nil
+}
+
+// ArticleExistsByContentHash checks if an article other than excludeID with the given
+// content hash was fetched since the given time
+func ArticleExistsByContentHash(db *sql.DB, hash string, excludeID string, since time.Time) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM articles WHERE content_hash = ? AND id != ? AND fetched_at >= ?`,
+		hash, excludeID, since.UTC()).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check article by content hash: %w", err)
+	}
+	return count > 0, nil
+}
+
@@ -0,0 +1,548 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestDB opens a fresh, migrated database in a temporary directory
+func openTestDB(t testing.TB) *sql.DB {
+	t.Helper()
+	db, err := InitDB(filepath.Join(t.TempDir(), "news.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// addTestFeed stores an enabled feed with the given ID
+func addTestFeed(t testing.TB, db *sql.DB, feedID string) {
+	t.Helper()
+	feed := &Feed{ID: feedID, Name: feedID, URL: "https://example.com/" + feedID + ".xml", Category: "news", Enabled: true}
+	if err := UpsertFeedSettings(db, feed); err != nil {
+		t.Fatalf("UpsertFeedSettings: %v", err)
+	}
+}
+
+// addTestArticle stores an unread article of feedID, published and fetched at published
+func addTestArticle(t testing.TB, db *sql.DB, feedID string, id string, published time.Time) *Article {
+	t.Helper()
+	article := &Article{
+		ID:          id,
+		FeedID:      feedID,
+		Title:       "Article " + id,
+		URL:         "https://example.com/" + id,
+		PublishedAt: published.UTC(),
+		FetchedAt:   published.UTC(),
+		SourceName:  feedID,
+	}
+	if err := UpsertArticle(db, article); err != nil {
+		t.Fatalf("UpsertArticle: %v", err)
+	}
+	return article
+}
+
+// getTestArticle loads an article, failing the test if it doesn't exist
+func getTestArticle(t testing.TB, db *sql.DB, id string) *Article {
+	t.Helper()
+	article, err := GetArticleByID(db, id)
+	if err != nil {
+		t.Fatalf("GetArticleByID(%s): %v", id, err)
+	}
+	return article
+}
+
+// articleExists reports whether an article with the given ID is stored
+func articleExists(t testing.TB, db *sql.DB, id string) bool {
+	t.Helper()
+	exists, err := AnyArticleExists(db, []string{id})
+	if err != nil {
+		t.Fatalf("AnyArticleExists: %v", err)
+	}
+	return exists
+}
+
+// inZone runs the test with the local time zone set to loc, restoring it afterwards
+func inZone(t *testing.T, loc *time.Location) {
+	t.Helper()
+	local := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = local })
+}
+
+func TestMarkOldUnreadReadBoundary(t *testing.T) {
+	// Away from UTC, a cutoff bound in local time would be off by the offset
+	for _, loc := range []*time.Location{time.UTC, time.FixedZone("UTC+5", 5*3600), time.FixedZone("UTC-8", -8*3600)} {
+		t.Run(loc.String(), func(t *testing.T) {
+			inZone(t, loc)
+			db := openTestDB(t)
+			addTestFeed(t, db, "f")
+
+			const window = 7 * 24 * time.Hour
+			now := time.Now()
+			addTestArticle(t, db, "f", "older", now.Add(-window-time.Minute))
+			addTestArticle(t, db, "f", "newer", now.Add(-window+time.Minute))
+			addTestArticle(t, db, "f", "saved", now.Add(-window-time.Hour))
+			if err := ToggleArticleSaved(db, "saved"); err != nil {
+				t.Fatalf("ToggleArticleSaved: %v", err)
+			}
+
+			updated, err := MarkOldUnreadRead(db, window)
+			if err != nil {
+				t.Fatalf("MarkOldUnreadRead: %v", err)
+			}
+			if updated != 1 {
+				t.Errorf("updated %d articles, want 1", updated)
+			}
+			for id, wantRead := range map[string]bool{"older": true, "newer": false, "saved": false} {
+				if a := getTestArticle(t, db, id); a.IsRead != wantRead {
+					t.Errorf("%s: is_read = %v, want %v", id, a.IsRead, wantRead)
+				}
+			}
+			if a := getTestArticle(t, db, "older"); a.ReadAt != nil {
+				t.Errorf("older: read_at = %v, want unset", a.ReadAt)
+			}
+		})
+	}
+}
+
+func TestToggleArticleQueuedKeepsReadStatus(t *testing.T) {
+	db := openTestDB(t)
+	addTestFeed(t, db, "f")
+	addTestArticle(t, db, "f", "unread", time.Now())
+	addTestArticle(t, db, "f", "read", time.Now())
+	if err := MarkArticleAsRead(db, "read"); err != nil {
+		t.Fatalf("MarkArticleAsRead: %v", err)
+	}
+
+	for _, id := range []string{"unread", "read"} {
+		if err := ToggleArticleQueued(db, id); err != nil {
+			t.Fatalf("ToggleArticleQueued(%s): %v", id, err)
+		}
+	}
+	if a := getTestArticle(t, db, "unread"); !a.IsQueued || a.IsRead {
+		t.Errorf("unread article after queuing: queued %v, read %v; want queued and unread", a.IsQueued, a.IsRead)
+	}
+	if a := getTestArticle(t, db, "read"); !a.IsQueued || !a.IsRead || a.ReadAt == nil {
+		t.Errorf("read article after queuing: queued %v, read %v, read_at %v; want queued and still read", a.IsQueued, a.IsRead, a.ReadAt)
+	}
+
+	if err := ToggleArticleQueued(db, "read"); err != nil {
+		t.Fatalf("ToggleArticleQueued: %v", err)
+	}
+	if a := getTestArticle(t, db, "read"); a.IsQueued || a.QueuedAt != nil || !a.IsRead {
+		t.Errorf("after unqueuing: queued %v, queued_at %v, read %v; want unqueued and still read", a.IsQueued, a.QueuedAt, a.IsRead)
+	}
+}
+
+// foreignKeyViolations returns the number of rows that break a foreign key
+func foreignKeyViolations(t testing.TB, db *sql.DB) int {
+	t.Helper()
+	rows, err := db.Query(`PRAGMA foreign_key_check;`)
+	if err != nil {
+		t.Fatalf("foreign_key_check: %v", err)
+	}
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	return n
+}
+
+func TestDeleteFeedKeepSaved(t *testing.T) {
+	db := openTestDB(t)
+	addTestFeed(t, db, "gone")
+	addTestFeed(t, db, "other")
+	addTestArticle(t, db, "gone", "saved", time.Now())
+	addTestArticle(t, db, "gone", "unsaved", time.Now())
+	addTestArticle(t, db, "other", "other", time.Now())
+	if err := ToggleArticleSaved(db, "saved"); err != nil {
+		t.Fatalf("ToggleArticleSaved: %v", err)
+	}
+
+	if err := DeleteFeed(db, "gone", true); err != nil {
+		t.Fatalf("DeleteFeed: %v", err)
+	}
+
+	if !articleExists(t, db, "saved") || articleExists(t, db, "unsaved") || !articleExists(t, db, "other") {
+		t.Error("want only the deleted feed's unsaved article removed")
+	}
+	if n := foreignKeyViolations(t, db); n != 0 {
+		t.Errorf("%d foreign key violations after deleting a feed", n)
+	}
+	if _, err := GetFeedByID(db, "gone"); err == nil {
+		t.Error("deleted feed is still found by GetFeedByID")
+	}
+	feeds, err := ListFeeds(db, false)
+	if err != nil {
+		t.Fatalf("ListFeeds: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].ID != "other" {
+		t.Errorf("ListFeeds returned %d feeds, want only \"other\"", len(feeds))
+	}
+	stats, err := GetStats(db)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.Feeds != 1 {
+		t.Errorf("stats count %d feeds, want 1", stats.Feeds)
+	}
+
+	// Once the kept article is gone, so is the feed's row
+	if err := ToggleArticleSaved(db, "saved"); err != nil {
+		t.Fatalf("ToggleArticleSaved: %v", err)
+	}
+	if _, err := PurgeNonSavedArticles(db); err != nil {
+		t.Fatalf("PurgeNonSavedArticles: %v", err)
+	}
+	if err := DeleteUnusedFeeds(db); err != nil {
+		t.Fatalf("DeleteUnusedFeeds: %v", err)
+	}
+	var rows int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM feeds WHERE id = 'gone';`).Scan(&rows); err != nil {
+		t.Fatalf("count feeds: %v", err)
+	}
+	if rows != 0 {
+		t.Error("deleted feed's row remains after its last article was removed")
+	}
+}
+
+func TestDeleteFeedWithoutKeepingSaved(t *testing.T) {
+	db := openTestDB(t)
+	addTestFeed(t, db, "gone")
+	addTestArticle(t, db, "gone", "saved", time.Now())
+	if err := ToggleArticleSaved(db, "saved"); err != nil {
+		t.Fatalf("ToggleArticleSaved: %v", err)
+	}
+
+	if err := DeleteFeed(db, "gone", false); err != nil {
+		t.Fatalf("DeleteFeed: %v", err)
+	}
+	if articleExists(t, db, "saved") {
+		t.Error("saved article kept without keepSaved")
+	}
+	var rows int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM feeds WHERE id = 'gone';`).Scan(&rows); err != nil {
+		t.Fatalf("count feeds: %v", err)
+	}
+	if rows != 0 {
+		t.Error("feed row kept without saved articles")
+	}
+}
+
+func TestReaddDeletedFeed(t *testing.T) {
+	db := openTestDB(t)
+	addTestFeed(t, db, "back")
+	addTestArticle(t, db, "back", "saved", time.Now())
+	if err := ToggleArticleSaved(db, "saved"); err != nil {
+		t.Fatalf("ToggleArticleSaved: %v", err)
+	}
+	if err := DeleteFeed(db, "back", true); err != nil {
+		t.Fatalf("DeleteFeed: %v", err)
+	}
+
+	addTestFeed(t, db, "back")
+	feed, err := GetFeedByID(db, "back")
+	if err != nil {
+		t.Fatalf("re-added feed not found: %v", err)
+	}
+	if !feed.Enabled {
+		t.Error("re-added feed is disabled")
+	}
+	if a := getTestArticle(t, db, "saved"); a.FeedID != "back" || !a.IsSaved {
+		t.Errorf("kept article: feed %s, saved %v", a.FeedID, a.IsSaved)
+	}
+}
+
+func TestFeedIDURLSurvivesURLEdit(t *testing.T) {
+	db := openTestDB(t)
+	addTestFeed(t, db, "f")
+	const original = "https://example.com/f.xml"
+
+	edits := []struct {
+		name   string
+		upsert func(*sql.DB, *Feed) error
+	}{
+		{"UpsertFeedSettings", UpsertFeedSettings},
+		{"UpsertFeed", UpsertFeed},
+	}
+	for i, edit := range edits {
+		url := fmt.Sprintf("https://example.org/moved-%d.xml", i)
+		if err := edit.upsert(db, &Feed{ID: "f", Name: "f", URL: url, Category: "news", Enabled: true}); err != nil {
+			t.Fatalf("%s: %v", edit.name, err)
+		}
+		feed, err := GetFeedByID(db, "f")
+		if err != nil {
+			t.Fatalf("GetFeedByID: %v", err)
+		}
+		if feed.URL != url || feed.ArticleIDURL() != original {
+			t.Errorf("after %s: url %q, article ID URL %q; want %q and %q", edit.name, feed.URL, feed.ArticleIDURL(), url, original)
+		}
+	}
+}
+
+// largeFeed returns n articles for feedID whose IDs start with prefix
+func largeFeed(feedID string, prefix string, n int) []*Article {
+	now := time.Now().UTC()
+	articles := make([]*Article, n)
+	for i := range articles {
+		articles[i] = &Article{
+			ID:          fmt.Sprintf("%s-%d", prefix, i),
+			FeedID:      feedID,
+			Title:       fmt.Sprintf("Article %d", i),
+			URL:         fmt.Sprintf("https://example.com/%s/%d", prefix, i),
+			Summary:     "A short summary of the article.",
+			Content:     "<p>The full text of the article, long enough to be realistic.</p>",
+			PublishedAt: now.Add(-time.Duration(i) * time.Minute),
+			FetchedAt:   now,
+			SourceName:  feedID,
+		}
+	}
+	return articles
+}
+
+func TestUpsertArticlesRollsBack(t *testing.T) {
+	db := openTestDB(t)
+	addTestFeed(t, db, "f")
+	addTestArticle(t, db, "f", "existing", time.Now())
+
+	batch := largeFeed("f", "new", 3)
+	changed := *getTestArticle(t, db, "existing")
+	changed.Title = "Changed title"
+	batch = append(batch, &changed)
+	// An article of a feed that doesn't exist fails the foreign key check
+	batch = append(batch, &Article{ID: "orphan", FeedID: "missing", Title: "Orphan", URL: "https://example.com/orphan"})
+
+	if err := UpsertArticles(db, batch); err == nil {
+		t.Fatal("UpsertArticles succeeded with an article of an unknown feed")
+	}
+	for _, a := range batch {
+		if a.ID != "existing" && articleExists(t, db, a.ID) {
+			t.Errorf("article %s was stored by the failed batch", a.ID)
+		}
+	}
+	if a := getTestArticle(t, db, "existing"); a.Title != "Article existing" {
+		t.Errorf("failed batch updated the existing article's title to %q", a.Title)
+	}
+
+	// Without the bad article, the whole batch is stored
+	if err := UpsertArticles(db, batch[:len(batch)-1]); err != nil {
+		t.Fatalf("UpsertArticles: %v", err)
+	}
+	for _, a := range batch[:len(batch)-1] {
+		if !articleExists(t, db, a.ID) {
+			t.Errorf("article %s missing after a successful batch", a.ID)
+		}
+	}
+	if a := getTestArticle(t, db, "existing"); a.Title != "Changed title" {
+		t.Errorf("existing article's title = %q, want it updated", a.Title)
+	}
+}
+
+// benchmarkFeedSize is the number of articles stored per iteration, a large feed
+const benchmarkFeedSize = 500
+
+// BenchmarkUpsertArticlesPerArticle stores a large feed with one UpsertArticle call,
+// and so one transaction, per article, as the scheduler did before UpsertArticles
+func BenchmarkUpsertArticlesPerArticle(b *testing.B) {
+	db := openTestDB(b)
+	addTestFeed(b, db, "f")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, a := range largeFeed("f", fmt.Sprint(i), benchmarkFeedSize) {
+			if err := UpsertArticle(db, a); err != nil {
+				b.Fatalf("UpsertArticle: %v", err)
+			}
+		}
+	}
+}
+
+// BenchmarkUpsertArticlesBatch stores a large feed in a single UpsertArticles transaction
+func BenchmarkUpsertArticlesBatch(b *testing.B) {
+	db := openTestDB(b)
+	addTestFeed(b, db, "f")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := UpsertArticles(db, largeFeed("f", fmt.Sprint(i), benchmarkFeedSize)); err != nil {
+			b.Fatalf("UpsertArticles: %v", err)
+		}
+	}
+}
+
+func TestDeleteExpiredArticlesExemptFeeds(t *testing.T) {
+	db := openTestDB(t)
+	addTestFeed(t, db, "reference")
+	addTestFeed(t, db, "news")
+
+	old := time.Now().Add(-100 * time.Hour)
+	addTestArticle(t, db, "reference", "reference-old", old)
+	addTestArticle(t, db, "news", "news-old", old)
+	addTestArticle(t, db, "news", "news-new", time.Now())
+
+	deleted, err := DeleteExpiredArticles(db, 72, []string{"reference"})
+	if err != nil {
+		t.Fatalf("DeleteExpiredArticles: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted %d articles, want 1", deleted)
+	}
+	for id, want := range map[string]bool{"reference-old": true, "news-old": false, "news-new": true} {
+		if got := articleExists(t, db, id); got != want {
+			t.Errorf("%s: exists = %v, want %v", id, got, want)
+		}
+	}
+
+	// Without the exemption the reference feed's article expires like any other
+	if _, err := DeleteExpiredArticles(db, 72, nil); err != nil {
+		t.Fatalf("DeleteExpiredArticles: %v", err)
+	}
+	if articleExists(t, db, "reference-old") {
+		t.Error("reference-old survived cleanup without an exemption")
+	}
+}
+
+func TestMarkFeedArticlesRead(t *testing.T) {
+	db := openTestDB(t)
+	addTestFeed(t, db, "noisy")
+	addTestFeed(t, db, "quiet")
+
+	now := time.Now()
+	addTestArticle(t, db, "noisy", "unread-1", now.Add(-time.Hour))
+	addTestArticle(t, db, "noisy", "unread-2", now.Add(-2*time.Hour))
+	addTestArticle(t, db, "noisy", "saved", now.Add(-3*time.Hour))
+	addTestArticle(t, db, "noisy", "already-read", now.Add(-4*time.Hour))
+	addTestArticle(t, db, "noisy", "old", now.Add(-10*24*time.Hour)) // outside the window
+	addTestArticle(t, db, "quiet", "other-feed", now.Add(-time.Hour))
+	if err := ToggleArticleSaved(db, "saved"); err != nil {
+		t.Fatalf("ToggleArticleSaved: %v", err)
+	}
+	if err := MarkArticleAsRead(db, "already-read"); err != nil {
+		t.Fatalf("MarkArticleAsRead: %v", err)
+	}
+	readAt := getTestArticle(t, db, "already-read").ReadAt
+
+	updated, err := MarkFeedArticlesRead(db, "noisy", 0)
+	if err != nil {
+		t.Fatalf("MarkFeedArticlesRead: %v", err)
+	}
+	if updated != 3 {
+		t.Errorf("updated %d articles, want 3", updated)
+	}
+
+	for id, wantRead := range map[string]bool{"unread-1": true, "unread-2": true, "saved": true, "already-read": true, "old": false, "other-feed": false} {
+		if a := getTestArticle(t, db, id); a.IsRead != wantRead {
+			t.Errorf("%s: is_read = %v, want %v", id, a.IsRead, wantRead)
+		}
+	}
+	if a := getTestArticle(t, db, "saved"); !a.IsSaved || a.SavedAt == nil {
+		t.Errorf("saved article: saved %v, saved_at %v; want it still saved", a.IsSaved, a.SavedAt)
+	}
+	if a := getTestArticle(t, db, "already-read"); a.ReadAt == nil || !a.ReadAt.Equal(*readAt) {
+		t.Errorf("already read article: read_at changed from %v to %v", readAt, a.ReadAt)
+	}
+
+	// A positive window replaces the default one
+	updated, err = MarkFeedArticlesRead(db, "noisy", 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("MarkFeedArticlesRead: %v", err)
+	}
+	if updated != 1 || !getTestArticle(t, db, "old").IsRead {
+		t.Errorf("with a 30 day window: updated %d, want the old article marked read", updated)
+	}
+}
+
+// queueTestArticles stores queued, unread articles with the given IDs
+func queueTestArticles(t *testing.T, db *sql.DB, feedID string, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		addTestArticle(t, db, feedID, id, time.Now())
+		if err := ToggleArticleQueued(db, id); err != nil {
+			t.Fatalf("ToggleArticleQueued(%s): %v", id, err)
+		}
+	}
+}
+
+func TestReadingDequeues(t *testing.T) {
+	tests := []struct {
+		name string
+		read func(t *testing.T, db *sql.DB)
+	}{
+		{"MarkArticleAsRead", func(t *testing.T, db *sql.DB) {
+			if err := MarkArticleAsRead(db, "a"); err != nil {
+				t.Fatalf("MarkArticleAsRead: %v", err)
+			}
+		}},
+		{"MarkArticlesAsRead", func(t *testing.T, db *sql.DB) {
+			if _, err := MarkArticlesAsRead(db, []string{"a"}); err != nil {
+				t.Fatalf("MarkArticlesAsRead: %v", err)
+			}
+		}},
+		{"MarkAllAsRead", func(t *testing.T, db *sql.DB) {
+			if _, err := MarkAllAsRead(db, ArticleFilter{View: "latest", FeedID: "f"}); err != nil {
+				t.Fatalf("MarkAllAsRead: %v", err)
+			}
+		}},
+		{"ToggleArticleRead", func(t *testing.T, db *sql.DB) {
+			if isRead, err := ToggleArticleRead(db, "a"); err != nil || !isRead {
+				t.Fatalf("ToggleArticleRead = %v, %v; want read", isRead, err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			addTestFeed(t, db, "f")
+			addTestFeed(t, db, "other")
+			queueTestArticles(t, db, "f", "a")
+			queueTestArticles(t, db, "other", "b")
+
+			tt.read(t, db)
+
+			if a := getTestArticle(t, db, "a"); !a.IsRead || a.IsQueued || a.QueuedAt != nil {
+				t.Errorf("read article: read %v, queued %v, queued_at %v; want read and out of the queue", a.IsRead, a.IsQueued, a.QueuedAt)
+			}
+			if b := getTestArticle(t, db, "b"); b.IsRead || !b.IsQueued || b.QueuedAt == nil {
+				t.Errorf("unrelated article: read %v, queued %v; want it still queued and unread", b.IsRead, b.IsQueued)
+			}
+		})
+	}
+}
+
+func TestQueuedReadArticleStaysQueued(t *testing.T) {
+	db := openTestDB(t)
+	addTestFeed(t, db, "f")
+	addTestArticle(t, db, "f", "a", time.Now())
+	if err := MarkArticleAsRead(db, "a"); err != nil {
+		t.Fatalf("MarkArticleAsRead: %v", err)
+	}
+	// Queuing an already-read article keeps it in the queue until it is removed
+	if err := ToggleArticleQueued(db, "a"); err != nil {
+		t.Fatalf("ToggleArticleQueued: %v", err)
+	}
+
+	if err := MarkArticleAsRead(db, "a"); err != nil {
+		t.Fatalf("MarkArticleAsRead: %v", err)
+	}
+	if _, err := MarkArticlesAsRead(db, []string{"a"}); err != nil {
+		t.Fatalf("MarkArticlesAsRead: %v", err)
+	}
+	if _, err := MarkAllAsRead(db, ArticleFilter{View: "latest"}); err != nil {
+		t.Fatalf("MarkAllAsRead: %v", err)
+	}
+	if a := getTestArticle(t, db, "a"); !a.IsQueued {
+		t.Error("marking an already read article read again took it out of the queue")
+	}
+
+	// Toggling it unread doesn't touch the queue either
+	if isRead, err := ToggleArticleRead(db, "a"); err != nil || isRead {
+		t.Fatalf("ToggleArticleRead = %v, %v; want unread", isRead, err)
+	}
+	if a := getTestArticle(t, db, "a"); !a.IsQueued || a.IsRead {
+		t.Errorf("after toggling unread: queued %v, read %v; want queued and unread", a.IsQueued, a.IsRead)
+	}
+}
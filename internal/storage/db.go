@@ -3,14 +3,38 @@ package storage
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	_ "github.com/ncruces/go-sqlite3/driver"
 	_ "github.com/ncruces/go-sqlite3/embed"
 )
 
+// busyTimeoutMillis is how long a connection waits for another connection's write lock
+// before failing with "database is locked"
+const busyTimeoutMillis = 10000
+
+// uriPathEscaper escapes the characters that would end the path part of a SQLite URI
+var uriPathEscaper = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23")
+
+// dataSourceName builds the connection string for the database at path. Every pooled
+// connection gets the same pragmas:
+//   - WAL journaling, so readers don't block the writer or each other
+//   - synchronous=NORMAL, which is safe with WAL and syncs to disk only at checkpoints;
+//     a power loss can lose the last few commits but never corrupts the database
+//   - a busy timeout, so concurrent writers wait for the lock instead of failing
+//   - foreign key enforcement, which SQLite leaves off by default
+//
+// Transactions start with BEGIN IMMEDIATE so they take the write lock up front. A
+// deferred transaction that reads first and writes later can't wait for the lock and
+// fails with "database is locked" regardless of the busy timeout.
+func dataSourceName(path string) string {
+	return fmt.Sprintf("file:%s?_txlock=immediate&_pragma=busy_timeout(%d)&_pragma=journal_mode(wal)&_pragma=synchronous(normal)&_pragma=foreign_keys(1)",
+		uriPathEscaper.Replace(path), busyTimeoutMillis)
+}
+
 // InitDB initializes a SQLite database connection
 func InitDB(path string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", path)
+	db, err := sql.Open("sqlite3", dataSourceName(path))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -25,79 +49,3 @@ func InitDB(path string) (*sql.DB, error) {
 
 	return db, nil
 }
-
-// RunMigrations creates the necessary tables if they don't exist
-func RunMigrations(db *sql.DB) error {
-	// Create feeds table
-	feedsTable := `
-	CREATE TABLE IF NOT EXISTS feeds (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		url TEXT NOT NULL,
-		category TEXT NOT NULL,
-		enabled INTEGER NOT NULL DEFAULT 1,
-		last_fetched_at DATETIME
-	);`
-
-	if _, err := db.Exec(feedsTable); err != nil {
-		return fmt.Errorf("failed to create feeds table: %w", err)
-	}
-
-	// Create articles table
-	articlesTable := `
-	CREATE TABLE IF NOT EXISTS articles (
-		id TEXT PRIMARY KEY,
-		feed_id TEXT NOT NULL,
-		title TEXT NOT NULL,
-		url TEXT NOT NULL,
-		summary TEXT,
-		content TEXT,
-		published_at DATETIME NOT NULL,
-		fetched_at DATETIME NOT NULL,
-		source_name TEXT NOT NULL,
-		categories TEXT,
-		is_read INTEGER DEFAULT 0,
-		is_saved INTEGER DEFAULT 0,
-		FOREIGN KEY (feed_id) REFERENCES feeds(id)
-	);`
-
-	if _, err := db.Exec(articlesTable); err != nil {
-		return fmt.Errorf("failed to create articles table: %w", err)
-	}
-
-	// Add is_saved column if it doesn't exist (for existing databases)
-	_, err := db.Exec(`ALTER TABLE articles ADD COLUMN is_saved INTEGER DEFAULT 0;`)
-	if err != nil {
-		// Column might already exist, ignore error
-	}
-
-	// Add is_trashed column if it doesn't exist (for existing databases)
-	_, _ = db.Exec(`ALTER TABLE articles ADD COLUMN is_trashed INTEGER DEFAULT 0;`)
-
-	// Create index on published_at for faster queries
-	indexQuery := `
-	CREATE INDEX IF NOT EXISTS idx_articles_published_at ON articles(published_at DESC);`
-
-	if _, err := db.Exec(indexQuery); err != nil {
-		return fmt.Errorf("failed to create index: %w", err)
-	}
-
-	// Create index on feed_id
-	feedIndexQuery := `
-	CREATE INDEX IF NOT EXISTS idx_articles_feed_id ON articles(feed_id);`
-
-	if _, err := db.Exec(feedIndexQuery); err != nil {
-		return fmt.Errorf("failed to create feed_id index: %w", err)
-	}
-
-	// Create index on title for duplicate detection
-	titleIndexQuery := `
-	CREATE INDEX IF NOT EXISTS idx_articles_title ON articles(title);`
-
-	if _, err := db.Exec(titleIndexQuery); err != nil {
-		return fmt.Errorf("failed to create title index: %w", err)
-	}
-
-	return nil
-}
-
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConnectionPragmas(t *testing.T) {
+	db := openTestDB(t)
+	// Pin one connection so every pragma is read from the same one
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn: %v", err)
+	}
+	defer conn.Close()
+
+	tests := []struct {
+		pragma string
+		want   string
+	}{
+		{"journal_mode", "wal"},
+		{"synchronous", "1"}, // NORMAL
+		{"foreign_keys", "1"},
+		{"busy_timeout", "10000"},
+	}
+	for _, tt := range tests {
+		var got string
+		if err := conn.QueryRowContext(context.Background(), "PRAGMA "+tt.pragma+";").Scan(&got); err != nil {
+			t.Fatalf("PRAGMA %s: %v", tt.pragma, err)
+		}
+		if got != tt.want {
+			t.Errorf("PRAGMA %s = %s, want %s", tt.pragma, got, tt.want)
+		}
+	}
+}
+
+// countArticles returns the number of stored articles, failing the test if the read
+// doesn't finish within timeout
+func countArticles(t *testing.T, db *sql.DB, timeout time.Duration) int {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM articles;`).Scan(&n); err != nil {
+		t.Fatalf("read during write: %v", err)
+	}
+	return n
+}
+
+func TestReadDuringWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "news.db")
+	writer, err := InitDB(path)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer writer.Close()
+	// A second handle on the same file, like the web server next to the scheduler
+	reader, err := InitDB(path)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer reader.Close()
+
+	addTestFeed(t, writer, "f")
+	addTestArticle(t, writer, "f", "committed", time.Now())
+
+	// Hold the write lock with an uncommitted insert
+	tx, err := writer.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(upsertArticleQuery, upsertArticleArgs(&Article{ID: "pending", FeedID: "f", Title: "Pending", URL: "https://example.com/pending"})...); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// The read neither waits for the writer nor sees its uncommitted row
+	start := time.Now()
+	if n := countArticles(t, reader, time.Second); n != 1 {
+		t.Errorf("read during write saw %d articles, want 1", n)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("read during write took %v, want it not to wait for the writer", d)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if n := countArticles(t, reader, time.Second); n != 2 {
+		t.Errorf("read after commit saw %d articles, want 2", n)
+	}
+
+	// A long read, like rendering a page, doesn't hold up the writer's commit either.
+	// Without WAL the commit would wait for the read to finish.
+	readTx, err := reader.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	defer readTx.Rollback()
+	var before int
+	if err := readTx.QueryRow(`SELECT COUNT(*) FROM articles;`).Scan(&before); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := writer.ExecContext(ctx, `DELETE FROM articles WHERE id = 'pending';`); err != nil {
+		t.Fatalf("write during read: %v", err)
+	}
+
+	// The open read keeps its snapshot
+	var during int
+	if err := readTx.QueryRow(`SELECT COUNT(*) FROM articles;`).Scan(&during); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if before != 2 || during != 2 {
+		t.Errorf("read transaction saw %d then %d articles, want 2 both times", before, during)
+	}
+}
+
+func TestWriteWaitsForWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "news.db")
+	first, err := InitDB(path)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer first.Close()
+	second, err := InitDB(path)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer second.Close()
+	addTestFeed(t, first, "f")
+
+	tx, err := first.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		// Blocks on the write lock until the first transaction commits
+		done <- UpsertArticles(second, []*Article{{ID: "second", FeedID: "f", Title: "Second", URL: "https://example.com/second"}})
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := tx.Exec(upsertArticleQuery, upsertArticleArgs(&Article{ID: "first", FeedID: "f", Title: "First", URL: "https://example.com/first"})...); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("concurrent write failed instead of waiting: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("concurrent write did not finish")
+	}
+	if !articleExists(t, first, "first") || !articleExists(t, first, "second") {
+		t.Error("want both writes stored")
+	}
+}
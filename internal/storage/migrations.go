@@ -0,0 +1,305 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// migration is one versioned schema change. Versions are applied in order, each in its
+// own transaction, and recorded in schema_migrations so they run exactly once.
+// Never edit or renumber a released migration; append a new one instead.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations lists every schema change in order. Version 1 is the baseline schema.
+var migrations = []migration{
+	{version: 1, name: "baseline schema", up: migrateBaseline},
+	{version: 2, name: "feed moved_to", up: migrateFeedMovedTo},
+	{version: 3, name: "article content_extracted", up: migrateArticleContentExtracted},
+	{version: 4, name: "feed muted_until", up: migrateFeedMutedUntil},
+	{version: 5, name: "feed display_order", up: migrateFeedDisplayOrder},
+	{version: 6, name: "feed schedule hints", up: migrateFeedScheduleHints},
+	{version: 7, name: "article language", up: migrateArticleLanguage},
+	{version: 8, name: "feed site link and icon", up: migrateFeedSiteInfo},
+	{version: 9, name: "article note", up: migrateArticleNote},
+	{version: 10, name: "article read-later queue", up: migrateArticleQueue},
+	{version: 11, name: "article content_hash", up: migrateArticleContentHash},
+	{version: 12, name: "feed deleted", up: migrateFeedDeleted},
+	{version: 13, name: "feed id_url", up: migrateFeedIDURL},
+}
+
+// RunMigrations brings the database schema up to date, applying any migrations that
+// haven't run yet
+func RunMigrations(db *sql.DB) error {
+	if _, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at DATETIME NOT NULL
+	);`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	current, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	// Databases created before versioned migrations have tables but no recorded version.
+	// Bring them up to the baseline the old way, then let the baseline stamp them.
+	if current == 0 {
+		legacy, err := tableExists(db, "articles")
+		if err != nil {
+			return err
+		}
+		if legacy {
+			slog.Info("Upgrading existing database to versioned migrations")
+			if err := upgradeLegacySchema(db); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schemaVersion returns the highest applied migration version, or 0 if none
+func schemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations;`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// tableExists reports whether a table with the given name exists
+func tableExists(db *sql.DB, name string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?;`, name).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for table %s: %w", name, err)
+	}
+	return count > 0, nil
+}
+
+// applyMigration runs a migration and records it in one transaction
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?);`, m.version, time.Now()); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+	}
+	slog.Info("Applied database migration", "version", m.version, "name", m.name)
+	return nil
+}
+
+// migrateBaseline creates the schema as it stood when versioned migrations were introduced.
+// It uses IF NOT EXISTS so it also stamps legacy databases upgraded by upgradeLegacySchema.
+func migrateBaseline(tx *sql.Tx) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS feeds (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			url TEXT NOT NULL,
+			category TEXT NOT NULL,
+			enabled INTEGER NOT NULL DEFAULT 1,
+			last_fetched_at DATETIME,
+			last_attempt_at DATETIME,
+			last_success_at DATETIME,
+			last_error TEXT,
+			consecutive_failures INTEGER NOT NULL DEFAULT 0
+		);`,
+		`CREATE TABLE IF NOT EXISTS articles (
+			id TEXT PRIMARY KEY,
+			feed_id TEXT NOT NULL,
+			title TEXT NOT NULL,
+			url TEXT NOT NULL,
+			summary TEXT,
+			content TEXT,
+			published_at DATETIME NOT NULL,
+			fetched_at DATETIME NOT NULL,
+			source_name TEXT NOT NULL,
+			categories TEXT,
+			is_read INTEGER DEFAULT 0,
+			is_saved INTEGER DEFAULT 0,
+			is_trashed INTEGER DEFAULT 0,
+			image_url TEXT NOT NULL DEFAULT '',
+			read_at DATETIME,
+			saved_at DATETIME,
+			FOREIGN KEY (feed_id) REFERENCES feeds(id)
+		);`,
+		// Faster view queries, per-feed lookups and duplicate detection by title
+		`CREATE INDEX IF NOT EXISTS idx_articles_published_at ON articles(published_at DESC);`,
+		`CREATE INDEX IF NOT EXISTS idx_articles_feed_id ON articles(feed_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_articles_title ON articles(title);`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateFeedMovedTo records where a feed has permanently moved
+func migrateFeedMovedTo(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE feeds ADD COLUMN moved_to TEXT NOT NULL DEFAULT '';`)
+	return err
+}
+
+// migrateArticleContentExtracted tracks full-text extraction per article:
+// 0 = not attempted, 1 = extracted, -1 = failed
+func migrateArticleContentExtracted(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE articles ADD COLUMN content_extracted INTEGER NOT NULL DEFAULT 0;`)
+	return err
+}
+
+// migrateFeedMutedUntil lets a feed be snoozed without disabling it
+func migrateFeedMutedUntil(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE feeds ADD COLUMN muted_until DATETIME;`)
+	return err
+}
+
+// migrateFeedDisplayOrder adds a user-defined feed order, starting from the
+// alphabetical order feeds were listed in before
+func migrateFeedDisplayOrder(tx *sql.Tx) error {
+	if _, err := tx.Exec(`ALTER TABLE feeds ADD COLUMN display_order INTEGER NOT NULL DEFAULT 0;`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`UPDATE feeds SET display_order = (
+		SELECT COUNT(*) FROM feeds f2
+		WHERE f2.name < feeds.name OR (f2.name = feeds.name AND f2.id < feeds.id)
+	);`)
+	return err
+}
+
+// migrateFeedScheduleHints stores the polling hints a feed publishes (RSS ttl,
+// skipHours and skipDays). Hours and weekdays are comma-separated numbers.
+func migrateFeedScheduleHints(tx *sql.Tx) error {
+	for _, stmt := range []string{
+		`ALTER TABLE feeds ADD COLUMN ttl_minutes INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE feeds ADD COLUMN skip_hours TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE feeds ADD COLUMN skip_days TEXT NOT NULL DEFAULT '';`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateArticleLanguage stores each article's declared or detected language code
+func migrateArticleLanguage(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE articles ADD COLUMN language TEXT NOT NULL DEFAULT '';`)
+	return err
+}
+
+// migrateFeedSiteInfo stores the homepage and favicon of each feed's website
+func migrateFeedSiteInfo(tx *sql.Tx) error {
+	if _, err := tx.Exec(`ALTER TABLE feeds ADD COLUMN site_url TEXT NOT NULL DEFAULT '';`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`ALTER TABLE feeds ADD COLUMN icon_url TEXT NOT NULL DEFAULT '';`)
+	return err
+}
+
+// migrateArticleNote adds an optional user note to articles; NULL means no note
+func migrateArticleNote(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE articles ADD COLUMN note TEXT;`)
+	return err
+}
+
+// migrateArticleQueue adds the read-later queue, which articles leave once read
+func migrateArticleQueue(tx *sql.Tx) error {
+	if _, err := tx.Exec(`ALTER TABLE articles ADD COLUMN is_queued INTEGER NOT NULL DEFAULT 0;`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`ALTER TABLE articles ADD COLUMN queued_at DATETIME;`)
+	return err
+}
+
+// migrateArticleContentHash adds the title and summary fingerprint used by content
+// dedup. Existing articles keep an empty hash until they're fetched again.
+func migrateArticleContentHash(tx *sql.Tx) error {
+	if _, err := tx.Exec(`ALTER TABLE articles ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_content_hash ON articles(content_hash);`)
+	return err
+}
+
+// migrateFeedDeleted lets a deleted feed's row stay behind, hidden, while saved
+// articles kept from it still reference it. Feeds deleted before this, whose row is
+// gone, get such a row back, named after the articles' source.
+func migrateFeedDeleted(tx *sql.Tx) error {
+	if _, err := tx.Exec(`ALTER TABLE feeds ADD COLUMN deleted INTEGER NOT NULL DEFAULT 0;`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`INSERT INTO feeds (id, name, url, category, enabled, deleted)
+		SELECT feed_id, MAX(source_name), '', '', 0, 1 FROM articles
+		WHERE feed_id NOT IN (SELECT id FROM feeds)
+		GROUP BY feed_id;`)
+	return err
+}
+
+// migrateFeedIDURL records the URL each feed's article IDs are derived from, so
+// editing a feed's URL doesn't give its articles new IDs. Existing feeds use their
+// current URL, which their articles' IDs were derived from.
+func migrateFeedIDURL(tx *sql.Tx) error {
+	if _, err := tx.Exec(`ALTER TABLE feeds ADD COLUMN id_url TEXT NOT NULL DEFAULT '';`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`UPDATE feeds SET id_url = url;`)
+	return err
+}
+
+// upgradeLegacySchema adds the columns introduced before versioned migrations to a
+// database created by an older version. Each ALTER fails harmlessly if the column
+// already exists.
+func upgradeLegacySchema(db *sql.DB) error {
+	legacyColumns := []string{
+		`ALTER TABLE articles ADD COLUMN is_saved INTEGER DEFAULT 0;`,
+		`ALTER TABLE articles ADD COLUMN is_trashed INTEGER DEFAULT 0;`,
+		`ALTER TABLE articles ADD COLUMN image_url TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE articles ADD COLUMN read_at DATETIME;`,
+		`ALTER TABLE articles ADD COLUMN saved_at DATETIME;`,
+		`ALTER TABLE feeds ADD COLUMN last_attempt_at DATETIME;`,
+		`ALTER TABLE feeds ADD COLUMN last_success_at DATETIME;`,
+		`ALTER TABLE feeds ADD COLUMN last_error TEXT;`,
+		`ALTER TABLE feeds ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0;`,
+	}
+	for _, stmt := range legacyColumns {
+		_, _ = db.Exec(stmt)
+	}
+
+	// Articles saved before saved_at existed get their fetch time as the best available approximation
+	if _, err := db.Exec(`UPDATE articles SET saved_at = fetched_at WHERE is_saved = 1 AND saved_at IS NULL;`); err != nil {
+		return fmt.Errorf("failed to backfill saved_at: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"calmnews/internal/storage"
+)
+
+// articlesResponse is the JSON payload returned by HandleAPIArticles
+type articlesResponse struct {
+	Articles      []*storage.Article `json:"articles"`
+	View          string             `json:"view"`
+	FeedID        string             `json:"feed"`
+	Category      string             `json:"category"`
+	ReadFilter    string             `json:"read"`
+	Page          int                `json:"page"`
+	PerPage       int                `json:"per_page"`
+	TotalPages    int                `json:"total_pages"`
+	HasNextPage   bool               `json:"has_next_page"`
+	HasPrevPage   bool               `json:"has_prev_page"`
+	FilteredCount int                `json:"filtered_count"`
+}
+
+// HandleAPIArticles returns a page of articles as JSON. It accepts the same
+// view/feed/category/read/page query parameters as the front page and applies the blocklist.
+func (s *Server) HandleAPIArticles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	f, err := s.parseViewParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := s.loadArticlePage(f, parsePage(r), parsePerPage(r))
+	if err != nil {
+		slog.Error("Error querying articles", "err", err)
+		http.Error(w, "Error querying articles", http.StatusInternalServerError)
+		return
+	}
+
+	articles := result.Articles
+	if articles == nil {
+		// Encode an empty page as [] rather than null
+		articles = []*storage.Article{}
+	}
+
+	writeJSON(w, articlesResponse{
+		Articles:      articles,
+		View:          f.View,
+		FeedID:        f.FeedID,
+		Category:      f.Category,
+		ReadFilter:    f.ReadFilter,
+		Page:          result.Page,
+		PerPage:       result.PerPage,
+		TotalPages:    result.TotalPages,
+		HasNextPage:   result.HasNextPage,
+		HasPrevPage:   result.Page > 1,
+		FilteredCount: result.FilteredCount,
+	})
+}
+
+// maxBlockedSkips bounds how many blocklisted articles HandleAPINextArticle skips in a row
+const maxBlockedSkips = 200
+
+// nextArticleResponse is the JSON payload returned by HandleAPINextArticle. Done is true,
+// and the other fields are empty, when there are no more unread articles.
+type nextArticleResponse struct {
+	Done  bool   `json:"done"`
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// HandleAPINextArticle returns the next unread article after the one given by the after
+// query parameter, or the first unread article without it, in the same order as the front
+// page. It accepts the view/feed/category query parameters and skips blocklisted articles.
+func (s *Server) HandleAPINextArticle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	f, err := s.parseViewParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var after *storage.Article
+	if afterID := r.URL.Query().Get("after"); afterID != "" {
+		after, err = storage.GetArticleByID(s.db, afterID)
+		if err != nil {
+			if errors.Is(err, storage.ErrArticleNotFound) {
+				http.Error(w, "Unknown article ID", http.StatusNotFound)
+				return
+			}
+			slog.Error("Error querying article", "err", err)
+			http.Error(w, "Error querying article", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	rules := s.filterRules()
+	for i := 0; i < maxBlockedSkips; i++ {
+		next, err := storage.NextUnreadArticle(s.db, f, after)
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			break
+		}
+		if err != nil {
+			slog.Error("Error querying next article", "err", err)
+			http.Error(w, "Error querying next article", http.StatusInternalServerError)
+			return
+		}
+		if rules.ShouldFilter(next) {
+			after = next
+			continue
+		}
+
+		writeJSON(w, nextArticleResponse{
+			ID:    next.ID,
+			Title: next.Title,
+			URL:   s.OutboundURL(next),
+		})
+		return
+	}
+
+	writeJSON(w, nextArticleResponse{Done: true})
+}
+
+// writeJSON encodes v as the JSON response body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Error encoding JSON response", "err", err)
+	}
+}
@@ -0,0 +1,48 @@
+package web
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"calmnews/internal/config"
+)
+
+// BasicAuth wraps next so that every request must carry the configured Basic auth
+// credentials. The username is compared in constant time and the password is checked
+// against its bcrypt hash. If auth is not configured, next is returned unchanged.
+func BasicAuth(next http.Handler, auth config.AuthConfig) http.Handler {
+	if !auth.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.ExemptStatic && strings.HasPrefix(r.URL.Path, "/static/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !auth.ProtectHealth && (r.URL.Path == "/healthz" || r.URL.Path == "/metrics") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		username, password, ok := r.BasicAuth()
+		if !ok || !checkCredentials(auth, username, password) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="CalmNews", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkCredentials reports whether username and password match the configured ones.
+// The bcrypt check always runs so a wrong username takes as long as a wrong password.
+func checkCredentials(auth config.AuthConfig, username, password string) bool {
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(auth.Username)) == 1
+	passOK := bcrypt.CompareHashAndPassword([]byte(auth.PasswordHash), []byte(password)) == nil
+	return userOK && passOK
+}
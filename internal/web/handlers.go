@@ -1,54 +1,97 @@
 package web
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
-	"io/fs"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"calmnews/internal/config"
+	"calmnews/internal/dedup"
+	"calmnews/internal/feeds"
 	"calmnews/internal/filter"
 	"calmnews/internal/storage"
 )
 
+// maxOPMLSize caps the size of an uploaded OPML file
+const maxOPMLSize = 5 * 1024 * 1024 // 5MB
+
+// maxNoteLength caps the length of an article note, in characters
+const maxNoteLength = 280
+
+// ndjsonFlushEvery is how many lines the NDJSON export writes between flushes
+const ndjsonFlushEvery = 100
+
 // Server holds the dependencies for HTTP handlers
 type Server struct {
-	db         *sql.DB
-	config     *config.Config
-	configPath string
+	db        *sql.DB
+	config    *config.Store
+	templates *template.Template
+	funcs     template.FuncMap
+	started   time.Time
 }
 
-// NewServer creates a new web server instance
-func NewServer(db *sql.DB, cfg *config.Config, configPath string) *Server {
-	return &Server{
-		db:         db,
-		config:     cfg,
-		configPath: configPath,
+// NewServer creates a new web server instance. It parses all page templates up front
+// and returns an error if any is missing or invalid.
+func NewServer(db *sql.DB, store *config.Store) (*Server, error) {
+	s := &Server{
+		db:      db,
+		config:  store,
+		started: time.Now(),
+	}
+
+	s.funcs = template.FuncMap{
+		"timeAgo":     s.FormatTimeAgo,
+		"localTime":   s.LocalTime,
+		"outboundURL": s.OutboundURL,
+		"feedHealth":  FeedHealth,
+	}
+	tmpl, err := parseTemplates(s.funcs, store.Get().UI.AssetsDir)
+	if err != nil {
+		return nil, err
 	}
+	s.templates = tmpl
+
+	return s, nil
 }
 
-// HandleIndex handles the main front page
-func (s *Server) HandleIndex(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	view := r.URL.Query().Get("view")
+// maxDateRangeDays caps the from/to date range so a query can't scan the whole database
+const maxDateRangeDays = 31
+
+// parseViewParams reads the view, feed, category, read filter, date range and sort parameters from the
+// query string or form body, falling back to defaults for missing or invalid values
+func (s *Server) parseViewParams(r *http.Request) (storage.ArticleFilter, error) {
+	view := r.FormValue("view")
 	if view == "" {
-		view = s.config.UI.DefaultView
+		view = s.config.Get().UI.DefaultView
 	}
-	if view != "latest" && view != "today" && view != "week" && view != "saved" {
+	if view != "latest" && view != "today" && view != "week" && view != "saved" && view != "history" && view != "queue" {
 		view = "latest"
 	}
 
-	feedID := r.URL.Query().Get("feed")
+	feedID := r.FormValue("feed")
 	if feedID == "" {
 		feedID = "all"
 	}
 
-	readFilter := r.URL.Query().Get("read")
+	category := r.FormValue("category")
+	if category == "" {
+		category = "all"
+	}
+
+	readFilter := r.FormValue("read")
 	if readFilter == "" {
 		readFilter = "all"
 	}
@@ -56,134 +99,855 @@ func (s *Server) HandleIndex(w http.ResponseWriter, r *http.Request) {
 		readFilter = "all"
 	}
 
-	pageStr := r.URL.Query().Get("page")
-	page := 1
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
+	loc := s.config.Get().UI.Location()
+	from, to, err := parseDateRange(r.FormValue("from"), r.FormValue("to"), loc)
+	if err != nil {
+		return storage.ArticleFilter{}, err
+	}
+
+	// Unknown sort orders fall back to the default rather than failing the request
+	sort := r.FormValue("sort")
+	if sort != storage.SortNewest && sort != storage.SortOldest {
+		sort = ""
+	}
+
+	return storage.ArticleFilter{
+		View:       view,
+		FeedID:     feedID,
+		Category:   category,
+		ReadFilter: readFilter,
+		Window:     s.config.Get().UI.ViewWindow(view),
+		From:       from,
+		To:         to,
+		Sort:       sort,
+		Location:   loc,
+	}, nil
+}
+
+// parseDateRange parses inclusive from/to dates (YYYY-MM-DD, in loc) into a [from, to)
+// time range. If only one date is given, the range is that single day. Both empty means no range.
+func parseDateRange(fromStr, toStr string, loc *time.Location) (time.Time, time.Time, error) {
+	if fromStr == "" && toStr == "" {
+		return time.Time{}, time.Time{}, nil
+	}
+	if fromStr == "" {
+		fromStr = toStr
+	}
+	if toStr == "" {
+		toStr = fromStr
+	}
+
+	from, err := time.ParseInLocation("2006-01-02", fromStr, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", fromStr)
+	}
+	to, err := time.ParseInLocation("2006-01-02", toStr, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", toStr)
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("to date %s is before from date %s", toStr, fromStr)
+	}
+
+	// to is inclusive, so the range ends at the start of the following day
+	to = to.AddDate(0, 0, 1)
+	if to.After(from.AddDate(0, 0, maxDateRangeDays)) {
+		return time.Time{}, time.Time{}, fmt.Errorf("date range is longer than %d days", maxDateRangeDays)
+	}
+	return from, to, nil
+}
+
+// articlePage is one page of blocklist-filtered articles for a view
+type articlePage struct {
+	Articles []*storage.Article
+	// Page is the page returned, which is the last page if a later one was requested
+	Page int
+	// TotalPages counts pages of the filtered articles; it is at least 1 so an empty
+	// view still has a page 1
+	TotalPages    int
+	PerPage       int
+	HasNextPage   bool
+	FilteredCount int
+}
+
+// maxViewArticles is how many articles a view loads before filtering and paginating,
+// which bounds how many pages a view can have
+const maxViewArticles = 300
+
+// filterRules builds the blocklist/allowlist rules from the current config
+func (s *Server) filterRules() filter.Rules {
+	cfg := s.config.Get()
+	return filter.Rules{
+		Blocklist:       slices.Concat(cfg.Blocklist, filter.RemotePhrases(cfg.RemoteBlocklistURLs)),
+		FeedBlocklists:  cfg.FeedBlocklists(),
+		Allowlist:       cfg.Allowlist,
+		DomainBlocklist: cfg.DomainBlocklist,
+		MatchContent:    cfg.BlocklistMatchContent,
+	}
+}
+
+// parsePage reads the 1-based page query parameter, defaulting to 1
+func parsePage(r *http.Request) int {
+	page := 1
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	return page
+}
+
+// parsePerPage reads the optional per_page query parameter, returning 0 if it's missing
+// or invalid so the configured page size is used
+func parsePerPage(r *http.Request) int {
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage < 0 {
+		return 0
+	}
+	return perPage
+}
+
+// loadArticlePage queries the articles for a view, applies the blocklist and returns the
+// requested page. A page past the end is clamped to the last page. perPage overrides the
+// configured page size if positive; either is kept within the allowed range.
+func (s *Server) loadArticlePage(f storage.ArticleFilter, page int, perPage int) (*articlePage, error) {
+	// Query articles (get a superset, we'll filter and paginate)
+	articles, err := storage.ListArticlesByView(s.db, f, maxViewArticles)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply blocklist filter
+	filteredArticles, filteredCount := filter.FilterArticlesWithRules(articles, s.filterRules())
+
+	// Hide near-duplicates of the same story from different feeds
+	if cfg := s.config.Get(); cfg.Articles.FuzzyDedup {
+		filteredArticles, _ = dedup.Articles(filteredArticles, cfg.Articles.FuzzyDedupThreshold)
+	}
+
+	// Paginate
+	itemsPerPage := s.config.Get().UI.PageSize(perPage)
+	totalPages := max(1, (len(filteredArticles)+itemsPerPage-1)/itemsPerPage)
+	page = min(max(page, 1), totalPages)
+	start := (page - 1) * itemsPerPage
+	end := min(start+itemsPerPage, len(filteredArticles))
+
+	var pageArticles []*storage.Article
+	if start < end {
+		pageArticles = filteredArticles[start:end]
+	}
+
+	return &articlePage{
+		Articles:      pageArticles,
+		Page:          page,
+		TotalPages:    totalPages,
+		PerPage:       itemsPerPage,
+		HasNextPage:   page < totalPages,
+		FilteredCount: filteredCount,
+	}, nil
+}
+
+// articleGroup is a run of articles from the same day, shown under a date header
+type articleGroup struct {
+	Label    string
+	Articles []*storage.Article
+}
+
+// groupByDay splits a page of articles into days in loc, labeled "Today", "Yesterday"
+// or the date. Articles are grouped by the time the view sorts them by, so the queue is
+// grouped by when articles were queued and history by when they were read. Days run
+// newest first, or oldest first for the queue and the oldest-first sort, and each day
+// keeps the page's order.
+func groupByDay(articles []*storage.Article, f storage.ArticleFilter, loc *time.Location, now time.Time) []articleGroup {
+	dateOf := func(a *storage.Article) time.Time {
+		var t *time.Time
+		switch f.View {
+		case "history":
+			t = a.ReadAt
+		case "saved":
+			t = a.SavedAt
+		case "queue":
+			t = a.QueuedAt
+		}
+		if t == nil {
+			return a.PublishedAt
+		}
+		return *t
+	}
+	ascending := f.View == "queue" || (f.Sort == storage.SortOldest && f.View != "history" && f.View != "saved")
+
+	var days []time.Time
+	byDay := make(map[time.Time][]*storage.Article)
+	for _, a := range articles {
+		t := dateOf(a).In(loc)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(byDay[day], a)
+	}
+	slices.SortFunc(days, func(a, b time.Time) int {
+		if ascending {
+			return a.Compare(b)
+		}
+		return b.Compare(a)
+	})
+
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	groups := make([]articleGroup, 0, len(days))
+	for _, day := range days {
+		label := day.Format("Monday, Jan 2")
+		switch {
+		case day.Equal(today):
+			label = "Today"
+		case day.Equal(today.AddDate(0, 0, -1)):
+			label = "Yesterday"
+		case day.Year() != today.Year():
+			label = day.Format("Monday, Jan 2, 2006")
+		}
+		groups = append(groups, articleGroup{Label: label, Articles: byDay[day]})
+	}
+	return groups
+}
+
+// HandleIndex handles the main front page
+func (s *Server) HandleIndex(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	cfg := s.config.Get()
+	f, err := s.parseViewParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	perPage := parsePerPage(r)
+	result, err := s.loadArticlePage(f, parsePage(r), perPage)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error querying articles: %v", err), http.StatusInternalServerError)
+		return
+	}
+	page := result.Page
+
+	// Get all feeds for the filter dropdown, and by ID for each article's site link and icon
+	feeds, _ := storage.ListFeeds(s.db, false)
+	feedsByID := make(map[string]*storage.Feed, len(feeds))
+	for _, feed := range feeds {
+		feedsByID[feed.ID] = feed
+	}
+
+	// Categories for the category tabs
+	categories, err := storage.ListCategories(s.db)
+	if err != nil {
+		slog.Error("Error listing categories", "err", err)
+	}
+
+	// Unread badges for the feed dropdown (counted before blocklist filtering)
+	unreadCounts, err := storage.UnreadCountsByFeed(s.db, cfg.UI.ViewWindow("latest"))
+	if err != nil {
+		slog.Error("Error counting unread articles", "err", err)
+	}
+
+	// "X unread of Y" for the current view (counted before blocklist filtering)
+	totalCount, unreadCount, err := storage.CountArticlesByView(s.db, f)
+	if err != nil {
+		slog.Error("Error counting articles", "err", err)
+	}
+
+	// Prepare template data
+	data := map[string]interface{}{
+		"Articles":          result.Articles,
+		"Groups":            groupByDay(result.Articles, f, cfg.UI.Location(), time.Now()),
+		"View":              f.View,
+		"FeedID":            f.FeedID,
+		"Category":          f.Category,
+		"ReadFilter":        f.ReadFilter,
+		"Sort":              f.Sort,
+		"From":              r.FormValue("from"),
+		"To":                r.FormValue("to"),
+		"Feeds":             feeds,
+		"FeedsByID":         feedsByID,
+		"Categories":        categories,
+		"UnreadCounts":      unreadCounts,
+		"TotalCount":        totalCount,
+		"UnreadCount":       unreadCount,
+		"Page":              page,
+		"TotalPages":        result.TotalPages,
+		"PerPageParam":      perPage > 0,
+		"PerPage":           result.PerPage,
+		"NextPage":          page + 1,
+		"PrevPage":          page - 1,
+		"HasNextPage":       result.HasNextPage,
+		"HasPrevPage":       page > 1,
+		"FilteredCount":     result.FilteredCount,
+		"ShowFilteredCount": cfg.UI.ShowFilteredCount,
+		"Theme":             cfg.UI.Theme,
+	}
+
+	if err := s.RenderTemplate(w, "index.html", data); err != nil {
+		slog.Error("Error rendering template", "err", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// HandleArticle renders a single article's content in the reader view and marks it as read
+func (s *Server) HandleArticle(w http.ResponseWriter, r *http.Request) {
+	articleID := r.URL.Query().Get("id")
+	if articleID == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	article, err := storage.GetArticleByID(s.db, articleID)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error querying article: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if !article.IsRead {
+		if err := storage.MarkArticleAsRead(s.db, article.ID); err != nil {
+			slog.Error("Error marking article as read", "article_id", article.ID, "err", err)
+		} else {
+			article.IsRead = true
+		}
+	}
+
+	data := map[string]interface{}{
+		"Article": article,
+		"Content": SanitizeHTML(article.Content),
+		"Theme":   s.config.Get().UI.Theme,
+	}
+
+	// Opening the reader view must always reach the server so the article gets marked read
+	w.Header().Set("Cache-Control", "no-store")
+	if err := s.RenderTemplate(w, "article.html", data); err != nil {
+		slog.Error("Error rendering template", "err", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// HandleOpenArticle marks an article as read and redirects to its outbound URL, so
+// click-throughs from the list are tracked without JavaScript
+func (s *Server) HandleOpenArticle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	articleID := r.URL.Query().Get("id")
+	if articleID == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	article, err := storage.GetArticleByID(s.db, articleID)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error querying article: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if article.URL == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !article.IsRead {
+		// Still redirect on failure; losing the read mark is better than losing the click
+		if err := storage.MarkArticleAsRead(s.db, article.ID); err != nil {
+			slog.Error("Error marking article as read", "article_id", article.ID, "err", err)
+		}
+	}
+
+	// Never let the redirect be cached, or reopening the article would skip the read mark
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, s.OutboundURL(article), http.StatusFound)
+}
+
+// shareResponse is the JSON payload returned by HandleShareArticle
+type shareResponse struct {
+	// Text is "title — source — url", leaving out any part that is empty
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// HandleShareArticle returns an article's title, source and link as ready-made share
+// text, plus the bare link, for copy-to-clipboard buttons and mailto links
+func (s *Server) HandleShareArticle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	articleID := r.URL.Query().Get("id")
+	if articleID == "" {
+		http.Error(w, "Article ID required", http.StatusBadRequest)
+		return
+	}
+
+	article, err := storage.GetArticleByID(s.db, articleID)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		slog.Error("Error querying article", "article_id", articleID, "err", err)
+		http.Error(w, "Error querying article", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, shareResponse{Text: shareText(article), URL: article.URL})
+}
+
+// shareText formats an article as "title — source — url" on one line
+func shareText(article *storage.Article) string {
+	var parts []string
+	for _, part := range []string{article.Title, article.SourceName, article.URL} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " — ")
+}
+
+// HandleSettings handles the settings page
+func (s *Server) HandleSettings(w http.ResponseWriter, r *http.Request) {
+	feeds, err := storage.ListFeeds(s.db, false)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error querying feeds: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	blockHits, err := s.blockHitsThisWeek()
+	if err != nil {
+		// The counts are informational; show the page without them
+		slog.Error("Error counting blocklist matches", "err", err)
+	}
+
+	cfg := s.config.Get()
+	data := map[string]interface{}{
+		"BlockHits":        blockHits,
+		"Blocklist":        cfg.Blocklist,
+		"RemoteBlocklists": filter.RemoteBlocklists(cfg.RemoteBlocklistURLs),
+		"Allowlist":        cfg.Allowlist,
+		"DomainBlocklist":  cfg.DomainBlocklist,
+		"URLBlocklist":     cfg.URLBlocklist,
+		"Feeds":            feeds,
+		"Now":              time.Now(),
+		"Theme":            cfg.UI.Theme,
+	}
+
+	if err := s.RenderTemplate(w, "settings.html", data); err != nil {
+		slog.Error("Error rendering template", "err", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// blockHitsThisWeek returns how many of the past week's articles each blocklist phrase
+// or blocked domain hid, streaming the articles rather than loading them all
+func (s *Server) blockHitsThisWeek() (map[string]int, error) {
+	rules := s.filterRules()
+	hits := make(map[string]int)
+	err := storage.IterateArticlesByView(s.db, storage.ArticleFilter{View: "week"}, func(a *storage.Article) error {
+		if entry := rules.Match(a); entry != "" {
+			hits[entry]++
+		}
+		return nil
+	})
+	return hits, err
+}
+
+// HandleUpdateBlocklist handles POST requests to update the blocklist
+func (s *Server) HandleUpdateBlocklist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	action := r.FormValue("action")
+	phrase := strings.TrimSpace(r.FormValue("phrase"))
+
+	err := s.config.Update(func(cfg *config.Config) error {
+		if action == "add" && phrase != "" {
+			cfg.Blocklist = addPhrase(cfg.Blocklist, phrase)
+		} else if action == "remove" && phrase != "" {
+			cfg.Blocklist = removePhrase(cfg.Blocklist, phrase)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Error saving config", "err", err)
+		http.Error(w, "Error saving config", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+}
+
+// HandleUpdateAllowlist handles POST requests to update the allowlist
+func (s *Server) HandleUpdateAllowlist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	action := r.FormValue("action")
+	phrase := strings.TrimSpace(r.FormValue("phrase"))
+
+	err := s.config.Update(func(cfg *config.Config) error {
+		if action == "add" && phrase != "" {
+			cfg.Allowlist = addPhrase(cfg.Allowlist, phrase)
+		} else if action == "remove" && phrase != "" {
+			cfg.Allowlist = removePhrase(cfg.Allowlist, phrase)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Error saving config", "err", err)
+		http.Error(w, "Error saving config", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+}
+
+// HandleUpdateDomainBlocklist handles POST requests to update the domain blocklist
+func (s *Server) HandleUpdateDomainBlocklist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	action := r.FormValue("action")
+	domain := filter.NormalizeDomain(r.FormValue("domain"))
+
+	err := s.config.Update(func(cfg *config.Config) error {
+		if action == "add" && domain != "" {
+			cfg.DomainBlocklist = addPhrase(cfg.DomainBlocklist, domain)
+		} else if action == "remove" && domain != "" {
+			cfg.DomainBlocklist = removePhrase(cfg.DomainBlocklist, domain)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Error saving config", "err", err)
+		http.Error(w, "Error saving config", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+}
+
+// addPhrase appends phrase to list unless it is already present (case-insensitive)
+func addPhrase(list []string, phrase string) []string {
+	lowerPhrase := strings.ToLower(phrase)
+	for _, p := range list {
+		if strings.ToLower(p) == lowerPhrase {
+			return list
+		}
+	}
+	return append(list, phrase)
+}
+
+// removePhrase returns list without phrase (case-insensitive)
+func removePhrase(list []string, phrase string) []string {
+	lowerPhrase := strings.ToLower(phrase)
+	var newList []string
+	for _, p := range list {
+		if strings.ToLower(p) != lowerPhrase {
+			newList = append(newList, p)
+		}
+	}
+	return newList
+}
+
+// HandleMarkArticleRead handles POST requests to mark an article as read
+func (s *Server) HandleMarkArticleRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	articleID := r.FormValue("id")
+	if articleID == "" {
+		http.Error(w, "Article ID required", http.StatusBadRequest)
+		return
+	}
+
+	if err := storage.MarkArticleAsRead(s.db, articleID); err != nil {
+		slog.Error("Error marking article as read", "article_id", articleID, "err", err)
+		http.Error(w, "Error marking article as read", http.StatusInternalServerError)
+		return
+	}
+
+	// Return JSON response for AJAX calls
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status": "ok"}`))
+}
+
+// HandleReadBeacon marks an article as read from a navigator.sendBeacon call. The ID
+// may be sent as an id form value or as the plain-text request body. It always answers
+// 204 for a well-formed request, since beacons ignore the response.
+func (s *Server) HandleReadBeacon(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	var articleID string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/plain") {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		articleID = strings.TrimSpace(string(body))
+	} else {
+		articleID = r.FormValue("id")
+	}
+	if articleID == "" {
+		http.Error(w, "Article ID required", http.StatusBadRequest)
+		return
+	}
+
+	if err := storage.MarkArticleAsRead(s.db, articleID); err != nil {
+		slog.Error("Error marking article as read", "article_id", articleID, "err", err)
+		http.Error(w, "Error marking article as read", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleMarkArticleUnread handles POST requests to mark an article as unread
+func (s *Server) HandleMarkArticleUnread(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	articleID := r.FormValue("id")
+	if articleID == "" {
+		http.Error(w, "Article ID required", http.StatusBadRequest)
+		return
+	}
+
+	if err := storage.MarkArticleAsUnread(s.db, articleID); err != nil {
+		slog.Error("Error marking article as unread", "article_id", articleID, "err", err)
+		http.Error(w, "Error marking article as unread", http.StatusInternalServerError)
+		return
+	}
+
+	// Return JSON response for AJAX calls
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status": "ok"}`))
+}
+
+// HandleToggleArticleRead handles POST requests to flip an article between read and
+// unread, responding with the new state
+func (s *Server) HandleToggleArticleRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	articleID := r.FormValue("id")
+	if articleID == "" {
+		http.Error(w, "Article ID required", http.StatusBadRequest)
+		return
+	}
+
+	isRead, err := storage.ToggleArticleRead(s.db, articleID)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		slog.Error("Error toggling article read status", "article_id", articleID, "err", err)
+		http.Error(w, "Error toggling article read status", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]bool{"is_read": isRead})
+}
+
+// purgeConfirmation must be sent as the confirm form value to purge articles, so a
+// stray request can't wipe the database
+const purgeConfirmation = "purge"
+
+// HandlePurgeArticles handles POST requests to delete every article that isn't saved
+// or queued, regardless of retention
+func (s *Server) HandlePurgeArticles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.FormValue("confirm") != purgeConfirmation {
+		http.Error(w, `Purging deletes all unsaved articles; send confirm=purge to proceed`, http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := storage.PurgeNonSavedArticles(s.db)
+	if err != nil {
+		slog.Error("Error purging articles", "err", err)
+		http.Error(w, "Error purging articles", http.StatusInternalServerError)
+		return
+	}
+	slog.Warn("Purged all unsaved articles", "count", deleted, "remote_addr", r.RemoteAddr)
+
+	writeJSON(w, map[string]interface{}{
+		"status":  "ok",
+		"deleted": deleted,
+	})
+}
+
+// HandleMarkAllRead handles POST requests to mark every article in the current view/feed as read
+func (s *Server) HandleMarkAllRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	f, err := s.parseViewParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Query articles (get a superset, we'll filter and paginate)
-	limit := 300 // Get more than we need for filtering
-	articles, err := storage.ListArticlesByView(s.db, view, feedID, readFilter, limit)
+	updated, err := storage.MarkAllAsRead(s.db, f)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error querying articles: %v", err), http.StatusInternalServerError)
+		slog.Error("Error marking all articles as read", "err", err)
+		http.Error(w, "Error marking articles as read", http.StatusInternalServerError)
 		return
 	}
 
-	// Apply blocklist filter
-	filteredArticles, filteredCount := filter.FilterArticles(articles, s.config.Blocklist)
+	writeJSON(w, map[string]interface{}{
+		"status":  "ok",
+		"updated": updated,
+	})
+}
 
-	// Paginate
-	itemsPerPage := s.config.UI.ItemsPerPage
-	start := (page - 1) * itemsPerPage
-	end := start + itemsPerPage
-	if start > len(filteredArticles) {
-		start = len(filteredArticles)
+// HandleMarkFeedRead handles POST requests to mark all of one feed's unread articles in
+// the Latest window as read, e.g. to clear a noisy feed from the feed filter
+func (s *Server) HandleMarkFeedRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
-	if end > len(filteredArticles) {
-		end = len(filteredArticles)
+
+	feedID := r.FormValue("feed_id")
+	if feedID == "" || feedID == "all" {
+		http.Error(w, "Feed ID required", http.StatusBadRequest)
+		return
 	}
 
-	var pageArticles []*storage.Article
-	if start < len(filteredArticles) {
-		pageArticles = filteredArticles[start:end]
+	updated, err := storage.MarkFeedArticlesRead(s.db, feedID, s.config.Get().UI.ViewWindow("latest"))
+	if err != nil {
+		slog.Error("Error marking feed read", "feed_id", feedID, "err", err)
+		http.Error(w, "Error marking feed read", http.StatusInternalServerError)
+		return
 	}
 
-	// Get all feeds for the filter dropdown
-	feeds, _ := storage.ListFeeds(s.db, false)
+	writeJSON(w, map[string]interface{}{
+		"status":  "ok",
+		"updated": updated,
+	})
+}
 
-	// Prepare template data
-	data := map[string]interface{}{
-		"Articles":          pageArticles,
-		"View":              view,
-		"FeedID":            feedID,
-		"ReadFilter":        readFilter,
-		"Feeds":             feeds,
-		"Page":              page,
-		"NextPage":          page + 1,
-		"PrevPage":          page - 1,
-		"HasNextPage":       end < len(filteredArticles),
-		"HasPrevPage":       page > 1,
-		"FilteredCount":     filteredCount,
-		"ShowFilteredCount": s.config.UI.ShowFilteredCount,
-		"Theme":             s.config.UI.Theme,
+// maxReadBatch caps how many articles one read-batch request may mark
+const maxReadBatch = 500
+
+// HandleMarkArticlesRead handles POST requests to mark several articles as read at once,
+// e.g. as they are scrolled past. IDs come either as a JSON body {"ids": [...]} or as a
+// comma-separated "ids" form value.
+func (s *Server) HandleMarkArticlesRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	if err := s.RenderTemplate(w, "index.html", data); err != nil {
-		log.Printf("Error rendering template: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var ids []string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body struct {
+			IDs []string `json:"ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		ids = body.IDs
+	} else {
+		ids = strings.Split(r.FormValue("ids"), ",")
 	}
-}
 
-// HandleSettings handles the settings page
-func (s *Server) HandleSettings(w http.ResponseWriter, r *http.Request) {
-	feeds, err := storage.ListFeeds(s.db, false)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Error querying feeds: %v", err), http.StatusInternalServerError)
+	// Drop blanks and repeats so the count reflects distinct articles
+	seen := make(map[string]bool)
+	var articleIDs []string
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			articleIDs = append(articleIDs, id)
+		}
+	}
+	if len(articleIDs) == 0 {
+		http.Error(w, "Article IDs required", http.StatusBadRequest)
 		return
 	}
-
-	data := map[string]interface{}{
-		"Blocklist":    s.config.Blocklist,
-		"URLBlocklist": s.config.URLBlocklist,
-		"Feeds":        feeds,
-		"Theme":        s.config.UI.Theme,
+	if len(articleIDs) > maxReadBatch {
+		http.Error(w, fmt.Sprintf("At most %d article IDs per request", maxReadBatch), http.StatusBadRequest)
+		return
 	}
 
-	if err := s.RenderTemplate(w, "settings.html", data); err != nil {
-		log.Printf("Error rendering template: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	updated, err := storage.MarkArticlesAsRead(s.db, articleIDs)
+	if err != nil {
+		slog.Error("Error marking articles as read", "err", err)
+		http.Error(w, "Error marking articles as read", http.StatusInternalServerError)
+		return
 	}
+
+	writeJSON(w, map[string]interface{}{
+		"status":  "ok",
+		"updated": updated,
+	})
 }
 
-// HandleUpdateBlocklist handles POST requests to update the blocklist
-func (s *Server) HandleUpdateBlocklist(w http.ResponseWriter, r *http.Request) {
+// HandleRefreshFeeds handles POST requests to fetch all enabled feeds, or a single feed
+// if feed_id is given, immediately. Fetching happens in the background.
+func (s *Server) HandleRefreshFeeds(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	action := r.FormValue("action")
-	phrase := strings.TrimSpace(r.FormValue("phrase"))
-
-	if action == "add" && phrase != "" {
-		// Check if already exists
-		exists := false
-		lowerPhrase := strings.ToLower(phrase)
-		for _, p := range s.config.Blocklist {
-			if strings.ToLower(p) == lowerPhrase {
-				exists = true
-				break
-			}
-		}
-		if !exists {
-			s.config.Blocklist = append(s.config.Blocklist, phrase)
-		}
-	} else if action == "remove" && phrase != "" {
-		lowerPhrase := strings.ToLower(phrase)
-		var newList []string
-		for _, p := range s.config.Blocklist {
-			if strings.ToLower(p) != lowerPhrase {
-				newList = append(newList, p)
-			}
-		}
-		s.config.Blocklist = newList
+	feedID := r.FormValue("feed_id")
+	if feedID == "all" {
+		feedID = ""
 	}
 
-	// Save config
-	if err := config.SaveConfig(s.configPath, s.config); err != nil {
-		log.Printf("Error saving config: %v", err)
-		http.Error(w, "Error saving config", http.StatusInternalServerError)
+	queued, err := feeds.RefreshNow(s.db, s.config.Get(), feedID)
+	if err != nil {
+		slog.Error("Error starting refresh", "err", err)
+		http.Error(w, "Error starting refresh", http.StatusInternalServerError)
 		return
 	}
 
-	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+	writeJSON(w, map[string]interface{}{
+		"status": "refreshing",
+		"feeds":  queued,
+	})
 }
 
-// HandleMarkArticleRead handles POST requests to mark an article as read
-func (s *Server) HandleMarkArticleRead(w http.ResponseWriter, r *http.Request) {
+// HandleToggleArticleSaved handles POST requests to toggle an article's saved status
+func (s *Server) HandleToggleArticleSaved(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -195,9 +959,9 @@ func (s *Server) HandleMarkArticleRead(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := storage.MarkArticleAsRead(s.db, articleID); err != nil {
-		log.Printf("Error marking article as read: %v", err)
-		http.Error(w, "Error marking article as read", http.StatusInternalServerError)
+	if err := storage.ToggleArticleSaved(s.db, articleID); err != nil {
+		slog.Error("Error toggling article saved status", "article_id", articleID, "err", err)
+		http.Error(w, "Error toggling article saved status", http.StatusInternalServerError)
 		return
 	}
 
@@ -206,8 +970,9 @@ func (s *Server) HandleMarkArticleRead(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status": "ok"}`))
 }
 
-// HandleToggleArticleSaved handles POST requests to toggle an article's saved status
-func (s *Server) HandleToggleArticleSaved(w http.ResponseWriter, r *http.Request) {
+// HandleToggleArticleQueued handles POST requests to add an article to the read-later
+// queue or remove it
+func (s *Server) HandleToggleArticleQueued(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -219,15 +984,46 @@ func (s *Server) HandleToggleArticleSaved(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := storage.ToggleArticleSaved(s.db, articleID); err != nil {
-		log.Printf("Error toggling article saved status: %v", err)
-		http.Error(w, "Error toggling article saved status", http.StatusInternalServerError)
+	if err := storage.ToggleArticleQueued(s.db, articleID); err != nil {
+		slog.Error("Error toggling article queued status", "article_id", articleID, "err", err)
+		http.Error(w, "Error toggling article queued status", http.StatusInternalServerError)
 		return
 	}
 
-	// Return JSON response for AJAX calls
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"status": "ok"}`))
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// HandleSetArticleNote sets or clears the one-line note on an article
+func (s *Server) HandleSetArticleNote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	articleID := r.FormValue("id")
+	if articleID == "" {
+		http.Error(w, "Article ID required", http.StatusBadRequest)
+		return
+	}
+
+	// Notes are a single line; fold any line breaks into spaces
+	note := strings.Join(strings.Fields(r.FormValue("note")), " ")
+	if utf8.RuneCountInString(note) > maxNoteLength {
+		http.Error(w, fmt.Sprintf("Note must be at most %d characters", maxNoteLength), http.StatusBadRequest)
+		return
+	}
+
+	if err := storage.SetArticleNote(s.db, articleID, note); err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		slog.Error("Error setting article note", "article_id", articleID, "err", err)
+		http.Error(w, "Error setting article note", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]string{"status": "ok", "note": note})
 }
 
 // HandleTrashArticle marks an article as trashed and adds its URL to the URL blocklist
@@ -245,7 +1041,7 @@ func (s *Server) HandleTrashArticle(w http.ResponseWriter, r *http.Request) {
 
 	articleURL, err := storage.TrashArticle(s.db, articleID)
 	if err != nil {
-		log.Printf("Error trashing article: %v", err)
+		slog.Error("Error trashing article", "article_id", articleID, "err", err)
 		http.Error(w, "Error trashing article", http.StatusInternalServerError)
 		return
 	}
@@ -253,16 +1049,19 @@ func (s *Server) HandleTrashArticle(w http.ResponseWriter, r *http.Request) {
 	// Add the URL to the URL blocklist if not already present
 	lowerURL := strings.ToLower(articleURL)
 	exists := false
-	for _, u := range s.config.URLBlocklist {
+	for _, u := range s.config.Get().URLBlocklist {
 		if strings.ToLower(u) == lowerURL {
 			exists = true
 			break
 		}
 	}
 	if !exists {
-		s.config.URLBlocklist = append(s.config.URLBlocklist, articleURL)
-		if err := config.SaveConfig(s.configPath, s.config); err != nil {
-			log.Printf("Error saving config after trash: %v", err)
+		err := s.config.Update(func(cfg *config.Config) error {
+			cfg.URLBlocklist = append(cfg.URLBlocklist, articleURL)
+			return nil
+		})
+		if err != nil {
+			slog.Error("Error saving config after trash", "err", err)
 		}
 	}
 
@@ -278,14 +1077,16 @@ func (s *Server) HandleUpdateTheme(w http.ResponseWriter, r *http.Request) {
 	}
 
 	theme := r.FormValue("theme")
-	validThemes := map[string]bool{"": true, "terminal": true, "military": true, "industrial": true, "space": true}
-	if !validThemes[theme] {
+	if !config.ValidTheme(theme) {
 		theme = ""
 	}
 
-	s.config.UI.Theme = theme
-	if err := config.SaveConfig(s.configPath, s.config); err != nil {
-		log.Printf("Error saving config: %v", err)
+	err := s.config.Update(func(cfg *config.Config) error {
+		cfg.UI.Theme = theme
+		return nil
+	})
+	if err != nil {
+		slog.Error("Error saving config", "err", err)
 		http.Error(w, "Error saving config", http.StatusInternalServerError)
 		return
 	}
@@ -305,15 +1106,18 @@ func (s *Server) HandleUpdateURLBlocklist(w http.ResponseWriter, r *http.Request
 
 	if action == "remove" && url != "" {
 		lowerURL := strings.ToLower(url)
-		var newList []string
-		for _, u := range s.config.URLBlocklist {
-			if strings.ToLower(u) != lowerURL {
-				newList = append(newList, u)
+		err := s.config.Update(func(cfg *config.Config) error {
+			var newList []string
+			for _, u := range cfg.URLBlocklist {
+				if strings.ToLower(u) != lowerURL {
+					newList = append(newList, u)
+				}
 			}
-		}
-		s.config.URLBlocklist = newList
-		if err := config.SaveConfig(s.configPath, s.config); err != nil {
-			log.Printf("Error saving config: %v", err)
+			cfg.URLBlocklist = newList
+			return nil
+		})
+		if err != nil {
+			slog.Error("Error saving config", "err", err)
 			http.Error(w, "Error saving config", http.StatusInternalServerError)
 			return
 		}
@@ -322,6 +1126,15 @@ func (s *Server) HandleUpdateURLBlocklist(w http.ResponseWriter, r *http.Request
 	http.Redirect(w, r, "/settings", http.StatusSeeOther)
 }
 
+// maxMuteDuration is the longest a feed can be muted; disable it for anything longer
+const maxMuteDuration = 30 * 24 * time.Hour
+
+// defaultFeedCategory is used for added feeds that have no category of their own
+const defaultFeedCategory = "general"
+
+// feedValidationTimeout bounds how long adding a feed waits for its URL to respond
+const feedValidationTimeout = 10 * time.Second
+
 // HandleUpdateFeeds handles POST requests to update feeds
 func (s *Server) HandleUpdateFeeds(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -338,26 +1151,161 @@ func (s *Server) HandleUpdateFeeds(w http.ResponseWriter, r *http.Request) {
 			if err == nil {
 				feed.Enabled = !feed.Enabled
 				if err := storage.UpsertFeed(s.db, feed); err != nil {
-					log.Printf("Error updating feed: %v", err)
+					slog.Error("Error updating feed", "feed_id", feedID, "err", err)
 				} else {
 					// Update config
-					for i := range s.config.Feeds {
-						if s.config.Feeds[i].ID == feedID {
-							s.config.Feeds[i].Enabled = feed.Enabled
-							break
+					s.updateConfigOrLog(func(cfg *config.Config) {
+						for i := range cfg.Feeds {
+							if cfg.Feeds[i].ID == feedID {
+								cfg.Feeds[i].Enabled = feed.Enabled
+								break
+							}
 						}
-					}
-					config.SaveConfig(s.configPath, s.config)
+					})
+				}
+			}
+		}
+	} else if action == "edit" {
+		feedID := r.FormValue("feed_id")
+		name := strings.TrimSpace(r.FormValue("name"))
+		feedURL := strings.TrimSpace(r.FormValue("url"))
+		category := strings.TrimSpace(r.FormValue("category"))
+
+		if !isValidFeedURL(feedURL) {
+			http.Error(w, "Invalid feed URL", http.StatusBadRequest)
+			return
+		}
+
+		if feedID != "" && name != "" && category != "" {
+			feed, err := storage.GetFeedByID(s.db, feedID)
+			if err == nil {
+				// Articles reference the feed by ID, so changing the URL keeps them attached
+				feed.Name = name
+				feed.URL = feedURL
+				feed.Category = category
+				if err := storage.UpsertFeed(s.db, feed); err != nil {
+					slog.Error("Error updating feed", "feed_id", feedID, "err", err)
+				} else {
+					// Update config
+					s.updateConfigOrLog(func(cfg *config.Config) {
+						for i := range cfg.Feeds {
+							if cfg.Feeds[i].ID == feedID {
+								cfg.Feeds[i].Name = name
+								cfg.Feeds[i].URL = feedURL
+								cfg.Feeds[i].Category = category
+								break
+							}
+						}
+					})
 				}
 			}
 		}
+	} else if action == "mute" {
+		feedID := r.FormValue("feed_id")
+		duration, err := time.ParseDuration(r.FormValue("duration"))
+		if err != nil || duration <= 0 || duration > maxMuteDuration {
+			http.Error(w, "Invalid mute duration", http.StatusBadRequest)
+			return
+		}
+		if feedID != "" {
+			if err := storage.SetFeedMutedUntil(s.db, feedID, time.Now().Add(duration)); err != nil {
+				slog.Error("Error muting feed", "feed_id", feedID, "err", err)
+			}
+		}
+	} else if action == "unmute" {
+		feedID := r.FormValue("feed_id")
+		if feedID != "" {
+			if err := storage.SetFeedMutedUntil(s.db, feedID, time.Time{}); err != nil {
+				slog.Error("Error unmuting feed", "feed_id", feedID, "err", err)
+			}
+		}
+	} else if action == "move" {
+		feedID := r.FormValue("feed_id")
+		offset := 0
+		switch r.FormValue("direction") {
+		case "up":
+			offset = -1
+		case "down":
+			offset = 1
+		default:
+			http.Error(w, "Invalid move direction", http.StatusBadRequest)
+			return
+		}
+		if feedID != "" {
+			if err := storage.MoveFeed(s.db, feedID, offset); err != nil {
+				slog.Error("Error moving feed", "feed_id", feedID, "err", err)
+			} else {
+				s.syncFeedOrderToConfig()
+			}
+		}
+	} else if action == "delete" {
+		feedID := r.FormValue("feed_id")
+		keepSaved := r.FormValue("keep_saved") != ""
+		if feedID != "" {
+			if err := storage.DeleteFeed(s.db, feedID, keepSaved); err != nil {
+				slog.Error("Error deleting feed", "feed_id", feedID, "err", err)
+			} else {
+				// Remove from config
+				s.updateConfigOrLog(func(cfg *config.Config) {
+					var newFeeds []config.FeedConfig
+					for _, f := range cfg.Feeds {
+						if f.ID != feedID {
+							newFeeds = append(newFeeds, f)
+						}
+					}
+					cfg.Feeds = newFeeds
+				})
+			}
+		}
 	} else if action == "add" {
 		feedID := strings.TrimSpace(r.FormValue("id"))
 		name := strings.TrimSpace(r.FormValue("name"))
 		url := strings.TrimSpace(r.FormValue("url"))
 		category := strings.TrimSpace(r.FormValue("category"))
 
-		if feedID != "" && name != "" && url != "" && category != "" {
+		if url != "" {
+			taken, err := s.takenFeedIDs()
+			if err != nil {
+				slog.Error("Error listing feeds", "err", err)
+				http.Error(w, "Error adding feed", http.StatusInternalServerError)
+				return
+			}
+			if feedID != "" && taken[feedID] {
+				http.Error(w, fmt.Sprintf("feed ID %q is already in use", feedID), http.StatusBadRequest)
+				return
+			}
+
+			// Catch typos and non-feed pages before they become dead subscriptions
+			ctx, cancel := context.WithTimeout(r.Context(), feedValidationTimeout)
+			meta, err := feeds.FetchFeedMeta(ctx, url)
+			cancel()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("%s is not a valid feed: %v", url, err), http.StatusBadRequest)
+				return
+			}
+
+			// Fields left blank default to what the feed says about itself
+			if name == "" {
+				name = meta.Title
+			}
+			if name == "" {
+				name = feedID
+			}
+			if name == "" {
+				name = feedHost(url)
+			}
+			if category == "" && len(meta.Categories) > 0 {
+				category = strings.ToLower(meta.Categories[0])
+			}
+			if category == "" {
+				category = defaultFeedCategory
+			}
+
+			// An explicit ID wins; otherwise derive one from the name that no feed uses yet
+			if feedID == "" {
+				feedID = config.UniqueID(config.Slugify(name), func(id string) bool { return taken[id] })
+			}
+
 			feed := &storage.Feed{
 				ID:       feedID,
 				Name:     name,
@@ -366,19 +1314,20 @@ func (s *Server) HandleUpdateFeeds(w http.ResponseWriter, r *http.Request) {
 				Enabled:  true,
 			}
 			if err := storage.UpsertFeed(s.db, feed); err != nil {
-				log.Printf("Error adding feed: %v", err)
+				slog.Error("Error adding feed", "feed_id", feedID, "url", url, "err", err)
 			} else {
 				// Add to config
 				refreshInterval := 10
-				s.config.Feeds = append(s.config.Feeds, config.FeedConfig{
-					ID:                     feedID,
-					Name:                   name,
-					URL:                    url,
-					Category:               category,
-					Enabled:                true,
-					RefreshIntervalMinutes: &refreshInterval,
+				s.updateConfigOrLog(func(cfg *config.Config) {
+					cfg.Feeds = append(cfg.Feeds, config.FeedConfig{
+						ID:                     feedID,
+						Name:                   name,
+						URL:                    url,
+						Category:               category,
+						Enabled:                true,
+						RefreshIntervalMinutes: &refreshInterval,
+					})
 				})
-				config.SaveConfig(s.configPath, s.config)
 			}
 		}
 	}
@@ -386,12 +1335,189 @@ func (s *Server) HandleUpdateFeeds(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/settings", http.StatusSeeOther)
 }
 
-// FormatTimeAgo formats a time as "X hours ago" or similar
-func FormatTimeAgo(t time.Time) string {
+// takenFeedIDs returns the IDs used by feeds in the database or the config
+func (s *Server) takenFeedIDs() (map[string]bool, error) {
+	existing, err := storage.ListFeeds(s.db, false)
+	if err != nil {
+		return nil, err
+	}
+
+	taken := make(map[string]bool)
+	for _, f := range existing {
+		taken[f.ID] = true
+	}
+	for _, f := range s.config.Get().Feeds {
+		taken[f.ID] = true
+	}
+	return taken, nil
+}
+
+// syncFeedOrderToConfig reorders the config's feeds to match the database display order,
+// so the order survives a database reset. Feeds only in the config keep their place at the end.
+func (s *Server) syncFeedOrderToConfig() {
+	ordered, err := storage.ListFeeds(s.db, false)
+	if err != nil {
+		slog.Error("Error listing feeds", "err", err)
+		return
+	}
+	position := make(map[string]int, len(ordered))
+	for i, f := range ordered {
+		position[f.ID] = i
+	}
+	rank := func(id string) int {
+		if i, ok := position[id]; ok {
+			return i
+		}
+		return len(ordered)
+	}
+	s.updateConfigOrLog(func(cfg *config.Config) {
+		slices.SortStableFunc(cfg.Feeds, func(a, b config.FeedConfig) int {
+			return rank(a.ID) - rank(b.ID)
+		})
+	})
+}
+
+// feedHost returns the host name of a feed URL, or "" if it can't be parsed
+func feedHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// updateConfigOrLog applies fn to the config and saves it, logging any error
+func (s *Server) updateConfigOrLog(fn func(cfg *config.Config)) {
+	err := s.config.Update(func(cfg *config.Config) error {
+		fn(cfg)
+		return nil
+	})
+	if err != nil {
+		slog.Error("Error saving config", "err", err)
+	}
+}
+
+// HandleExportNDJSON streams articles matching the view/feed/category/read filters as
+// newline-delimited JSON, one article per line
+func (s *Server) HandleExportNDJSON(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	f, err := s.parseViewParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	count := 0
+	err = storage.IterateArticlesByView(s.db, f, func(a *storage.Article) error {
+		// Encode writes a trailing newline after each value
+		if err := enc.Encode(a); err != nil {
+			return err
+		}
+		count++
+		if flusher != nil && count%ndjsonFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Headers are already sent, so the best we can do is log and stop
+		slog.Error("Error exporting articles", "err", err)
+		return
+	}
+
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// HandleImportOPML handles POST requests that upload an OPML file of feeds to subscribe to
+func (s *Server) HandleImportOPML(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxOPMLSize)
+	file, _, err := r.FormFile("opml")
+	if err != nil {
+		http.Error(w, "OPML file required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Error reading OPML file", http.StatusBadRequest)
+		return
+	}
+
+	opmlFeeds, err := feeds.ParseOPML(data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid OPML file: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	imported := 0
+	err = s.config.Update(func(cfg *config.Config) error {
+		imported = feeds.ImportOPMLFeeds(s.db, cfg, opmlFeeds)
+		return nil
+	})
+	slog.Info("Imported feeds from OPML", "count", imported, "total", len(opmlFeeds))
+	if err != nil {
+		slog.Error("Error saving config", "err", err)
+		http.Error(w, "Error saving config", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+}
+
+// isValidFeedURL reports whether raw is an absolute http(s) URL with a host
+func isValidFeedURL(raw string) bool {
+	if raw == "" {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// FeedHealth classifies a feed's fetch health by consecutive failures:
+// "ok" for none, "warn" for one or two, and "error" for three or more
+func FeedHealth(feed *storage.Feed) string {
+	switch {
+	case feed.ConsecutiveFailures == 0:
+		return "ok"
+	case feed.ConsecutiveFailures < 3:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+// FormatTimeAgo formats a time as "X hours ago" or similar, or with the configured
+// layout when ui.time_display is "absolute"
+func (s *Server) FormatTimeAgo(t time.Time) string {
+	ui := s.config.Get().UI
+	t = t.In(ui.Location())
+	if ui.TimeDisplay == config.TimeDisplayAbsolute {
+		return t.Format(ui.TimeLayout())
+	}
+
 	now := time.Now()
 	diff := now.Sub(t)
 
-	if diff < time.Minute {
+	if diff < ui.JustNow() {
 		return "just now"
 	} else if diff < time.Hour {
 		minutes := int(diff.Minutes())
@@ -416,27 +1542,59 @@ func FormatTimeAgo(t time.Time) string {
 	}
 }
 
-// RenderTemplate renders an HTML template
+// LocalTime converts t to the configured time zone, for templates that format times
+// themselves
+func (s *Server) LocalTime(t time.Time) time.Time {
+	return t.In(s.config.Get().UI.Location())
+}
+
+// OutboundURL returns the link to use for an article, applying its feed's URL template if one is configured.
+// The stored article URL is never modified. A template that doesn't produce a valid link is ignored.
+func (s *Server) OutboundURL(article *storage.Article) string {
+	for _, feedCfg := range s.config.Get().Feeds {
+		if feedCfg.ID == article.FeedID {
+			link, err := feedCfg.OutboundURL(article.URL)
+			if err != nil {
+				slog.Warn("Ignoring invalid URL template", "feed_id", feedCfg.ID, "err", err)
+				break
+			}
+			return link
+		}
+	}
+	return article.URL
+}
+
+// RenderTemplate renders an HTML template. The page is rendered into a buffer first, so
+// a template error results in a clean error response rather than a half-written page.
 func (s *Server) RenderTemplate(w http.ResponseWriter, name string, data interface{}) error {
-	tmpl, err := template.New(name).Funcs(template.FuncMap{
-		"timeAgo": FormatTimeAgo,
-	}).ParseFS(templatesFS, "templates/"+name)
-	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
+	tmpl := s.templates
+	if assetsDir := s.config.Get().UI.AssetsDir; assetsDir != "" {
+		// Re-read overrides on every render so edits show up without a restart
+		var err error
+		tmpl, err = parseTemplates(s.funcs, assetsDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return fmt.Errorf("failed to execute template %s: %w", name, err)
 	}
 
-	return tmpl.Execute(w, data)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err := buf.WriteTo(w)
+	return err
 }
 
-// HandleStatic serves static files (CSS, etc.)
-func HandleStatic(w http.ResponseWriter, r *http.Request) {
-	// Create a sub filesystem for static files
-	staticFS, err := fs.Sub(templatesFS, "static")
+// HandleStatic serves static files (CSS, etc.), preferring files in ui.assets_dir
+func (s *Server) HandleStatic(w http.ResponseWriter, r *http.Request) {
+	staticFiles, err := staticFS(s.config.Get().UI.AssetsDir)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
 	// Strip the /static/ prefix and serve the file
-	http.StripPrefix("/static/", http.FileServer(http.FS(staticFS))).ServeHTTP(w, r)
+	http.StripPrefix("/static/", http.FileServer(http.FS(staticFiles))).ServeHTTP(w, r)
 }
@@ -0,0 +1,387 @@
+package web
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"calmnews/internal/config"
+	"calmnews/internal/storage"
+)
+
+// twoFeedConfig returns a config with the feeds "test" and "other"
+func twoFeedConfig() *config.Config {
+	return &config.Config{
+		Feeds: []config.FeedConfig{
+			{ID: "test", Name: "Test", URL: "https://example.com/feed.xml", Category: "news", Enabled: true},
+			{ID: "other", Name: "Other", URL: "https://example.org/feed.xml", Category: "tech", Enabled: true},
+		},
+		UI: config.UIConfig{ItemsPerPage: config.DefaultItemsPerPage, DefaultView: "latest"},
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	s, db := newTestServer(t, twoFeedConfig())
+	articles := addTestArticles(t, db, "test", ndjsonFlushEvery*2+7)
+	addTestArticles(t, db, "other", 5)
+	for _, a := range articles[:10] {
+		if err := storage.MarkArticleAsRead(db, a.ID); err != nil {
+			t.Fatalf("MarkArticleAsRead: %v", err)
+		}
+	}
+
+	n := len(articles)
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", n + 5},
+		{"?feed=test", n},
+		{"?feed=other", 5},
+		{"?read=unread", n + 5 - 10},
+		{"?category=tech", 5},
+		{"?view=saved", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query := tt.query
+			r := httptest.NewRequest(http.MethodGet, "/export.ndjson"+query, nil)
+			f, err := s.parseViewParams(r)
+			if err != nil {
+				t.Fatalf("parseViewParams: %v", err)
+			}
+			total, _, err := storage.CountArticlesByView(db, f)
+			if err != nil {
+				t.Fatalf("CountArticlesByView: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			s.HandleExportNDJSON(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+
+			lines := 0
+			scanner := bufio.NewScanner(w.Body)
+			for scanner.Scan() {
+				var a storage.Article
+				if err := json.Unmarshal(scanner.Bytes(), &a); err != nil {
+					t.Fatalf("line %d is not valid JSON: %v", lines+1, err)
+				}
+				if a.ID == "" {
+					t.Errorf("line %d has no article ID", lines+1)
+				}
+				lines++
+			}
+			if lines != total || lines != tt.want {
+				t.Errorf("exported %d lines, want %d (query count %d)", lines, tt.want, total)
+			}
+		})
+	}
+}
+
+func TestExportNDJSONMethod(t *testing.T) {
+	s, _ := newTestServer(t, nil)
+	w := httptest.NewRecorder()
+	s.HandleExportNDJSON(w, httptest.NewRequest(http.MethodPost, "/export.ndjson", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", w.Code)
+	}
+}
+
+// postForm builds a form-encoded POST request
+func postForm(target string, form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestMarkArticleUnread(t *testing.T) {
+	s, db := newTestServer(t, nil)
+	article := addTestArticles(t, db, "test", 1)[0]
+	if err := storage.MarkArticleAsRead(db, article.ID); err != nil {
+		t.Fatalf("MarkArticleAsRead: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	s.HandleMarkArticleUnread(w, postForm("/article/unread", url.Values{"id": {article.ID}}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if ct := w.Result().Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	got, err := storage.GetArticleByID(db, article.ID)
+	if err != nil {
+		t.Fatalf("GetArticleByID: %v", err)
+	}
+	if got.IsRead || got.ReadAt != nil {
+		t.Errorf("after undo: read %v, read_at %v; want unread", got.IsRead, got.ReadAt)
+	}
+}
+
+func TestMarkArticleUnreadBadRequests(t *testing.T) {
+	s, db := newTestServer(t, nil)
+	article := addTestArticles(t, db, "test", 1)[0]
+	if err := storage.MarkArticleAsRead(db, article.ID); err != nil {
+		t.Fatalf("MarkArticleAsRead: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"missing ID", postForm("/article/unread", nil), http.StatusBadRequest},
+		{"empty ID", postForm("/article/unread", url.Values{"id": {""}}), http.StatusBadRequest},
+		{"GET", httptest.NewRequest(http.MethodGet, "/article/unread?id="+article.ID, nil), http.StatusMethodNotAllowed},
+		{"DELETE", httptest.NewRequest(http.MethodDelete, "/article/unread?id="+article.ID, nil), http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.HandleMarkArticleUnread(w, tt.req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	// None of the rejected requests touched the article
+	got, err := storage.GetArticleByID(db, article.ID)
+	if err != nil {
+		t.Fatalf("GetArticleByID: %v", err)
+	}
+	if !got.IsRead {
+		t.Error("rejected request marked the article unread")
+	}
+}
+
+func TestAddFeedGeneratesID(t *testing.T) {
+	feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Feed</title></channel></rss>`))
+	}))
+	t.Cleanup(feedSrv.Close)
+
+	cfg := twoFeedConfig()
+	cfg.Feeds[0].ID = "cafe-news"
+	s, db := newTestServer(t, cfg)
+
+	added := 0
+	add := func(id string, name string) {
+		t.Helper()
+		added++
+		w := httptest.NewRecorder()
+		form := url.Values{"action": {"add"}, "id": {id}, "name": {name}, "url": {fmt.Sprintf("%s/%d.xml", feedSrv.URL, added)}}
+		s.HandleUpdateFeeds(w, postForm("/settings/feeds", form))
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("add %q: status = %d, want 303: %s", name, w.Code, w.Body)
+		}
+	}
+	add("", "Café News")               // collides with the configured "cafe-news"
+	add("", "Café  News")              // and then with the feed just added
+	add("", "Morning Digest")          // no collision
+	add("my-digest", "Morning Digest") // an explicit ID is used as given
+
+	for _, id := range []string{"cafe-news-2", "cafe-news-3", "morning-digest", "my-digest"} {
+		if _, err := storage.GetFeedByID(db, id); err != nil {
+			t.Errorf("feed %q not added: %v", id, err)
+		}
+	}
+
+	// An explicit ID that is already taken is rejected, leaving the existing feed alone
+	w := httptest.NewRecorder()
+	form := url.Values{"action": {"add"}, "id": {"cafe-news"}, "name": {"Impostor"}, "url": {feedSrv.URL + "/impostor.xml"}}
+	s.HandleUpdateFeeds(w, postForm("/settings/feeds", form))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("add with taken ID: status = %d, want 400", w.Code)
+	}
+	existing, err := storage.GetFeedByID(db, "cafe-news")
+	if err != nil {
+		t.Fatalf("GetFeedByID: %v", err)
+	}
+	if existing.Name != "Test" || existing.URL != cfg.Feeds[0].URL {
+		t.Errorf("existing feed overwritten: name %q, url %q", existing.Name, existing.URL)
+	}
+	got := make(map[string]bool)
+	for _, f := range s.config.Get().Feeds {
+		got[f.ID] = true
+		if f.ID == "cafe-news" && f.Name != "Test" {
+			t.Errorf("configured feed overwritten: name %q", f.Name)
+		}
+	}
+	if len(got) != 6 || !got["cafe-news-2"] || !got["cafe-news-3"] || !got["morning-digest"] || !got["my-digest"] {
+		t.Errorf("config feed IDs = %v", got)
+	}
+}
+
+func TestPerPageClamp(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured int
+		query      string
+		want       int
+	}{
+		{"zero config", 0, "", config.DefaultItemsPerPage},
+		{"huge config", 1000000, "", config.MaxItemsPerPage},
+		{"zero query", 20, "?per_page=0", 20},
+		{"huge query", 20, "?per_page=999999999", config.MaxItemsPerPage},
+		{"tiny query", 20, "?per_page=1", config.MinItemsPerPage},
+		{"negative query", 20, "?per_page=-5", 20},
+		{"invalid query", 20, "?per_page=lots", 20},
+		{"query in range", 20, "?per_page=7", 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := twoFeedConfig()
+			cfg.UI.ItemsPerPage = tt.configured
+			s, db := newTestServer(t, cfg)
+			addTestArticles(t, db, "test", 250)
+
+			w := httptest.NewRecorder()
+			s.HandleAPIArticles(w, httptest.NewRequest(http.MethodGet, "/api/articles"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+			}
+			var resp articlesResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.PerPage != tt.want || len(resp.Articles) != tt.want {
+				t.Errorf("per_page = %d with %d articles, want %d", resp.PerPage, len(resp.Articles), tt.want)
+			}
+			if wantPages := (250 + tt.want - 1) / tt.want; resp.TotalPages != wantPages {
+				t.Errorf("total_pages = %d, want %d", resp.TotalPages, wantPages)
+			}
+
+			// The front page renders with the same page size
+			w = httptest.NewRecorder()
+			s.HandleIndex(w, httptest.NewRequest(http.MethodGet, "/"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("index status = %d, want 200", w.Code)
+			}
+		})
+	}
+}
+
+func TestMarkFeedRead(t *testing.T) {
+	s, db := newTestServer(t, twoFeedConfig())
+	articles := addTestArticles(t, db, "test", 4)
+	addTestArticles(t, db, "other", 2)
+	if err := storage.MarkArticleAsRead(db, articles[0].ID); err != nil {
+		t.Fatalf("MarkArticleAsRead: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	s.HandleMarkFeedRead(w, postForm("/feeds/mark-read", url.Values{"feed_id": {"test"}}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Updated int `json:"updated"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Updated != 3 {
+		t.Errorf("updated = %d, want 3", resp.Updated)
+	}
+	counts, err := storage.UnreadCountsByFeed(db, 0)
+	if err != nil {
+		t.Fatalf("UnreadCountsByFeed: %v", err)
+	}
+	if counts["test"] != 0 || counts["other"] != 2 {
+		t.Errorf("unread counts = %v, want test cleared and other untouched", counts)
+	}
+
+	for _, tt := range []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"missing feed", postForm("/feeds/mark-read", nil), http.StatusBadRequest},
+		{"all feeds", postForm("/feeds/mark-read", url.Values{"feed_id": {"all"}}), http.StatusBadRequest},
+		{"GET", httptest.NewRequest(http.MethodGet, "/feeds/mark-read?feed_id=test", nil), http.StatusMethodNotAllowed},
+	} {
+		w := httptest.NewRecorder()
+		s.HandleMarkFeedRead(w, tt.req)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestOutboundURL(t *testing.T) {
+	const link = "https://example.com/post?id=1"
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"template", "https://archive.ph/newest/{url}", "https://archive.ph/newest/" + url.QueryEscape(link)},
+		{"template with query", "https://proxy.example.net/read?u={url}&ref=calmnews", "https://proxy.example.net/read?u=" + url.QueryEscape(link) + "&ref=calmnews"},
+		{"empty template", "", link},
+		{"no placeholder", "https://archive.ph/newest/", link},
+		{"relative result", "/read?u={url}", link},
+		{"non-http result", "javascript:alert({url})", link},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := twoFeedConfig()
+			cfg.Feeds[0].URLTemplate = tt.template
+			s, _ := newTestServer(t, cfg)
+			if got := s.OutboundURL(&storage.Article{FeedID: "test", URL: link}); got != tt.want {
+				t.Errorf("OutboundURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Articles of other feeds keep their link
+	cfg := twoFeedConfig()
+	cfg.Feeds[0].URLTemplate = "https://archive.ph/newest/{url}"
+	s, _ := newTestServer(t, cfg)
+	if got := s.OutboundURL(&storage.Article{FeedID: "other", URL: link}); got != link {
+		t.Errorf("other feed: OutboundURL = %q, want %q", got, link)
+	}
+}
+
+func TestArticleUsesOutboundURL(t *testing.T) {
+	cfg := twoFeedConfig()
+	cfg.Feeds[0].URLTemplate = "https://archive.ph/newest/{url}"
+	s, db := newTestServer(t, cfg)
+	article := addTestArticles(t, db, "test", 1)[0]
+	want := "https://archive.ph/newest/" + url.QueryEscape(article.URL)
+
+	w := httptest.NewRecorder()
+	s.HandleArticle(w, httptest.NewRequest(http.MethodGet, "/article?id="+article.ID, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("reader view: status = %d, want 200", w.Code)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `href="`+want+`"`) {
+		t.Errorf("reader view does not link to %s", want)
+	}
+
+	w = httptest.NewRecorder()
+	s.HandleOpenArticle(w, httptest.NewRequest(http.MethodGet, "/article/open?id="+article.ID, nil))
+	if loc := w.Result().Header.Get("Location"); w.Code != http.StatusFound || loc != want {
+		t.Errorf("open: status %d, Location %q; want 302 to %s", w.Code, loc, want)
+	}
+
+	// Only the link shown is rewritten, not the stored article
+	got, err := storage.GetArticleByID(db, article.ID)
+	if err != nil {
+		t.Fatalf("GetArticleByID: %v", err)
+	}
+	if got.URL != article.URL {
+		t.Errorf("stored URL = %q, want %q", got.URL, article.URL)
+	}
+}
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"calmnews/internal/storage"
+)
+
+// healthResponse is the JSON body returned by /healthz
+type healthResponse struct {
+	Status        string     `json:"status"`
+	UptimeSeconds int64      `json:"uptime_seconds"`
+	Feeds         int        `json:"feeds"`
+	Articles      int        `json:"articles"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+}
+
+// HandleHealthz reports uptime and basic database totals as JSON. It returns 503 if
+// the database can't be queried, so supervisors can restart a wedged process.
+func (s *Server) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats, err := storage.GetStats(s.db)
+	if err != nil {
+		slog.Error("Health check failed", "err", err)
+		// The header must be set before the status is written, or it is dropped
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		writeJSON(w, healthResponse{Status: "error", UptimeSeconds: s.uptimeSeconds()})
+		return
+	}
+
+	writeJSON(w, healthResponse{
+		Status:        "ok",
+		UptimeSeconds: s.uptimeSeconds(),
+		Feeds:         stats.Feeds,
+		Articles:      stats.Articles,
+		LastSuccessAt: stats.LastSuccessAt,
+	})
+}
+
+// HandleMetrics serves the same totals in the Prometheus text exposition format
+func (s *Server) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats, err := storage.GetStats(s.db)
+	if err != nil {
+		slog.Error("Error collecting metrics", "err", err)
+		http.Error(w, "Error collecting metrics", http.StatusServiceUnavailable)
+		return
+	}
+
+	var lastSuccess int64
+	if stats.LastSuccessAt != nil {
+		lastSuccess = stats.LastSuccessAt.Unix()
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetric(w, "calmnews_uptime_seconds", "gauge", "Seconds since the server started.", s.uptimeSeconds())
+	writeMetric(w, "calmnews_feeds", "gauge", "Number of feeds.", stats.Feeds)
+	writeMetric(w, "calmnews_feeds_enabled", "gauge", "Number of enabled feeds.", stats.EnabledFeeds)
+	writeMetric(w, "calmnews_feeds_failing", "gauge", "Number of feeds whose last fetch failed.", stats.FailingFeeds)
+	writeMetric(w, "calmnews_articles", "gauge", "Number of stored articles.", stats.Articles)
+	writeMetric(w, "calmnews_articles_unread", "gauge", "Number of unread articles.", stats.UnreadArticles)
+	writeMetric(w, "calmnews_last_success_timestamp_seconds", "gauge", "Unix time of the most recent successful feed fetch, 0 if none.", lastSuccess)
+}
+
+// writeMetric writes a single unlabeled metric with its HELP and TYPE lines
+func writeMetric(w http.ResponseWriter, name, kind, help string, value interface{}) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+}
+
+// uptimeSeconds returns how long the server has been running
+func (s *Server) uptimeSeconds() int64 {
+	return int64(time.Since(s.started).Seconds())
+}
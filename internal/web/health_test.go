@@ -0,0 +1,44 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthz(t *testing.T) {
+	s, db := newTestServer(t, nil)
+	addTestArticles(t, db, "test", 3)
+
+	w := httptest.NewRecorder()
+	s.HandleHealthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var got healthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.Status != "ok" || got.Feeds != 1 || got.Articles != 3 {
+		t.Errorf("response = %+v, want status ok with 1 feed and 3 articles", got)
+	}
+}
+
+func TestHealthzDatabaseError(t *testing.T) {
+	s, db := newTestServer(t, nil)
+	db.Close()
+
+	w := httptest.NewRecorder()
+	s.HandleHealthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", w.Code)
+	}
+	if ct := w.Result().Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var got healthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Status != "error" {
+		t.Errorf("body = %s, want JSON with status error", w.Body)
+	}
+}
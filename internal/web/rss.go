@@ -0,0 +1,89 @@
+package web
+
+import (
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"calmnews/internal/storage"
+)
+
+// rssFeed is an RSS 2.0 document
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description,omitempty"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// HandleSavedRSS serves the saved articles, newest first, as an RSS 2.0 feed so they
+// can be read in any feed reader
+func (s *Server) HandleSavedRSS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Links point back at this server, using whatever host the reader subscribed with
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	baseURL := scheme + "://" + r.Host
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       "CalmNews Saved Articles",
+			Link:        baseURL + "/?view=saved",
+			Description: "Articles saved in CalmNews",
+		},
+	}
+
+	err := storage.IterateArticlesByView(s.db, storage.ArticleFilter{View: "saved"}, func(a *storage.Article) error {
+		item := rssItem{
+			Title:       a.Title,
+			Link:        a.URL,
+			Description: a.Summary,
+			GUID:        rssGUID{Value: a.ID},
+		}
+		if !a.PublishedAt.IsZero() {
+			item.PubDate = a.PublishedAt.UTC().Format(time.RFC1123Z)
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+		return nil
+	})
+	if err != nil {
+		slog.Error("Error querying saved articles", "err", err)
+		http.Error(w, "Error querying saved articles", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		slog.Error("Error encoding RSS feed", "err", err)
+	}
+}
@@ -0,0 +1,108 @@
+package web
+
+import (
+	"html/template"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// allowedTags lists the elements kept by SanitizeHTML, with the attributes each may carry
+var allowedTags = map[string][]string{
+	"a": {"href", "title"}, "abbr": {"title"}, "b": nil, "blockquote": nil, "br": nil,
+	"caption": nil, "code": nil, "dd": nil, "del": nil, "div": nil, "dl": nil, "dt": nil,
+	"em": nil, "figcaption": nil, "figure": nil, "h1": nil, "h2": nil, "h3": nil,
+	"h4": nil, "h5": nil, "h6": nil, "hr": nil, "i": nil, "img": {"src", "alt", "title"},
+	"li": nil, "ol": nil, "p": nil, "pre": nil, "q": nil, "s": nil, "small": nil,
+	"span": nil, "strong": nil, "sub": nil, "sup": nil, "table": nil, "tbody": nil,
+	"td": nil, "tfoot": nil, "th": nil, "thead": nil, "tr": nil, "u": nil, "ul": nil,
+}
+
+// droppedTags are elements whose contents are removed along with the tags
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"noscript": true, "template": true, "form": true, "svg": true, "math": true,
+}
+
+// SanitizeHTML reduces untrusted feed HTML to a safe subset for the reader view.
+// Unknown tags are unwrapped, dangerous ones are dropped with their contents, and
+// only http(s) links and image sources are kept. Links open in a new tab.
+func SanitizeHTML(s string) template.HTML {
+	z := html.NewTokenizer(strings.NewReader(s))
+	var b strings.Builder
+	dropDepth := 0
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return template.HTML(b.String())
+		case html.TextToken:
+			if dropDepth == 0 {
+				b.WriteString(html.EscapeString(string(z.Text())))
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			tag := token.Data
+
+			if droppedTags[tag] {
+				if tt == html.StartTagToken {
+					dropDepth++
+				} else if tt == html.EndTagToken && dropDepth > 0 {
+					dropDepth--
+				}
+				continue
+			}
+			if dropDepth > 0 {
+				continue
+			}
+
+			allowedAttrs, ok := allowedTags[tag]
+			if !ok {
+				continue
+			}
+
+			if tt == html.EndTagToken {
+				b.WriteString("</" + tag + ">")
+				continue
+			}
+
+			b.WriteString("<" + tag)
+			for _, attr := range token.Attr {
+				if !containsString(allowedAttrs, attr.Key) {
+					continue
+				}
+				if (attr.Key == "href" || attr.Key == "src") && !isSafeURL(attr.Val) {
+					continue
+				}
+				b.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+			}
+			if tag == "a" {
+				b.WriteString(` target="_blank" rel="noopener noreferrer"`)
+			}
+			if tt == html.SelfClosingTagToken {
+				b.WriteString("/")
+			}
+			b.WriteString(">")
+		}
+	}
+}
+
+// isSafeURL reports whether raw is an absolute http(s) URL
+func isSafeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "http" || u.Scheme == "https"
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
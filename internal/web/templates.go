@@ -2,8 +2,73 @@ package web
 
 import (
 	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
 )
 
 //go:embed templates/*.html static/*.css
 var templatesFS embed.FS
 
+// pageTemplates are the templates the handlers render; NewServer fails if any is missing
+var pageTemplates = []string{"index.html", "article.html", "settings.html"}
+
+// parseTemplates parses every page template once, so handlers don't re-read and re-parse
+// them on each request. Each template is named after its file. If assetsDir is set, any
+// templates/*.html files under it replace the embedded templates of the same name.
+func parseTemplates(funcs template.FuncMap, assetsDir string) (*template.Template, error) {
+	tmpl, err := template.New("").Funcs(funcs).ParseFS(templatesFS, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	if assetsDir != "" {
+		overrides, err := filepath.Glob(filepath.Join(assetsDir, "templates", "*.html"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list template overrides: %w", err)
+		}
+		if len(overrides) > 0 {
+			if _, err := tmpl.ParseFiles(overrides...); err != nil {
+				return nil, fmt.Errorf("failed to parse template overrides: %w", err)
+			}
+		}
+	}
+
+	for _, name := range pageTemplates {
+		if tmpl.Lookup(name) == nil {
+			return nil, fmt.Errorf("template %s is missing", name)
+		}
+	}
+	return tmpl, nil
+}
+
+// overlayFS serves files from primary, falling back to fallback for files primary doesn't have
+type overlayFS struct {
+	primary  fs.FS
+	fallback fs.FS
+}
+
+// Open implements fs.FS
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.primary.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.fallback.Open(name)
+	}
+	return f, err
+}
+
+// staticFS returns the static files to serve: those under assetsDir/static, if set, with
+// the embedded files filling in anything missing
+func staticFS(assetsDir string) (fs.FS, error) {
+	embedded, err := fs.Sub(templatesFS, "static")
+	if err != nil {
+		return nil, err
+	}
+	if assetsDir == "" {
+		return embedded, nil
+	}
+	return overlayFS{primary: os.DirFS(filepath.Join(assetsDir, "static")), fallback: embedded}, nil
+}
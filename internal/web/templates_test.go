@@ -0,0 +1,106 @@
+package web
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"calmnews/internal/config"
+	"calmnews/internal/storage"
+)
+
+// newTestServer returns a Server backed by a fresh database and a config file in a
+// temporary directory. A nil cfg uses a config with a single feed, "test".
+func newTestServer(tb testing.TB, cfg *config.Config) (*Server, *sql.DB) {
+	tb.Helper()
+	dir := tb.TempDir()
+	db, err := storage.InitDB(filepath.Join(dir, "news.db"))
+	if err != nil {
+		tb.Fatalf("InitDB: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	if cfg == nil {
+		cfg = &config.Config{
+			Feeds: []config.FeedConfig{{ID: "test", Name: "Test", URL: "https://example.com/feed.xml", Category: "news", Enabled: true}},
+			UI:    config.UIConfig{ItemsPerPage: config.DefaultItemsPerPage, DefaultView: "latest"},
+		}
+	}
+	path := filepath.Join(dir, "config.yaml")
+	if err := config.SaveConfig(path, cfg); err != nil {
+		tb.Fatalf("SaveConfig: %v", err)
+	}
+	for _, f := range cfg.Feeds {
+		feed := &storage.Feed{ID: f.ID, Name: f.Name, URL: f.URL, Category: f.Category, Enabled: f.Enabled}
+		if err := storage.UpsertFeedSettings(db, feed); err != nil {
+			tb.Fatalf("UpsertFeedSettings: %v", err)
+		}
+	}
+
+	s, err := NewServer(db, config.NewStore(path, cfg))
+	if err != nil {
+		tb.Fatalf("NewServer: %v", err)
+	}
+	return s, db
+}
+
+// addTestArticles stores n unread articles for feedID, published a minute apart
+// going back from now, and returns them newest first
+func addTestArticles(tb testing.TB, db *sql.DB, feedID string, n int) []*storage.Article {
+	tb.Helper()
+	now := time.Now().UTC()
+	articles := make([]*storage.Article, n)
+	for i := range articles {
+		articles[i] = &storage.Article{
+			ID:          fmt.Sprintf("%s-%d", feedID, i),
+			FeedID:      feedID,
+			Title:       fmt.Sprintf("Article %d", i),
+			URL:         fmt.Sprintf("https://example.com/%s/%d", feedID, i),
+			Summary:     "A short summary of the article for the list view.",
+			PublishedAt: now.Add(-time.Duration(i) * time.Minute),
+			FetchedAt:   now,
+			SourceName:  feedID,
+		}
+	}
+	if err := storage.UpsertArticles(db, articles); err != nil {
+		tb.Fatalf("UpsertArticles: %v", err)
+	}
+	return articles
+}
+
+// benchmarkIndex renders the front page with 50 articles b.N times
+func benchmarkIndex(b *testing.B, assetsDir string) {
+	s, db := newTestServer(b, nil)
+	addTestArticles(b, db, "test", 50)
+	if assetsDir != "" {
+		cfg := s.config.Get().Clone()
+		cfg.UI.AssetsDir = assetsDir
+		s.config = config.NewStore(s.config.Path(), cfg)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		s.HandleIndex(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			b.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+	}
+}
+
+// BenchmarkIndexCachedTemplates executes the templates parsed once by NewServer
+func BenchmarkIndexCachedTemplates(b *testing.B) {
+	benchmarkIndex(b, "")
+}
+
+// BenchmarkIndexParsePerRequest re-parses the templates on every render, as happens
+// when an assets directory is set (and as every request did before templates were
+// cached). The directory is empty, so the same embedded templates are parsed.
+func BenchmarkIndexParsePerRequest(b *testing.B) {
+	benchmarkIndex(b, b.TempDir())
+}